@@ -0,0 +1,228 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// ArchiveExts is the set of file extensions recognized as archives, mapped to
+// their archive format.
+var ArchiveExts = map[string]string{
+	".cbz":    "zip",
+	".zip":    "zip",
+	".tar":    "tar",
+	".tar.gz": "tgz",
+	".tgz":    "tgz",
+}
+
+// archiveFormat returns the archive format of the file at p, or an empty
+// string if it is not an archive.
+func archiveFormat(p string) string {
+	p = strings.ToLower(p)
+
+	for ext, f := range ArchiveExts {
+		if strings.HasSuffix(p, ext) {
+			return f
+		}
+	}
+
+	return ""
+}
+
+// archiveName returns the base name of the archive at p without its
+// extension.
+func archiveName(p string) string {
+	base := path.Base(strings.Replace(p, "\\", "/", -1))
+
+	for ext := range ArchiveExts {
+		if strings.HasSuffix(strings.ToLower(base), ext) {
+			return base[:len(base)-len(ext)]
+		}
+	}
+
+	return base
+}
+
+// archives are the input archives whose entries are being read. ZIP and tar
+// archives are kept open, so their entries may be read at any time until the
+// archives are closed.
+type archives struct {
+	files []io.Closer
+}
+
+// read calls fn for every image entry of the archive at p, in archive order.
+// Entry names use forward slashes.
+func (a *archives) read(p string, fn func(entry string, src source) error) error {
+	switch archiveFormat(p) {
+	case "zip":
+		return a.readZip(p, fn)
+	case "tar":
+		return a.readTar(p, fn)
+	case "tgz":
+		return readTgz(p, fn)
+	}
+
+	return nil
+}
+
+// readZip reads the entries of a ZIP archive. The archive is kept open until
+// a is closed, so its entries may be read at any time by the given sources.
+func (a *archives) readZip(p string, fn func(string, source) error) error {
+	zr, err := zip.OpenReader(p)
+
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() || !isImage(zf.Name) {
+			continue
+		}
+
+		zf := zf
+
+		src := source{
+			name: p + ":" + zf.Name,
+			open: func() (io.ReadCloser, error) { return zf.Open() },
+		}
+
+		if err := fn(zf.Name, src); err != nil {
+			closeFile(p, zr)
+			return err
+		}
+	}
+
+	a.files = append(a.files, zr)
+	return nil
+}
+
+// readTar reads the entries of an uncompressed tar archive. The archive is
+// kept open until a is closed, so its entries are read in place by the given
+// sources.
+func (a *archives) readTar(p string, fn func(string, source) error) error {
+	f, err := os.Open(p)
+
+	if err != nil {
+		return err
+	}
+
+	err = readTarEntries(f, fn, func(tr *tar.Reader, hdr *tar.Header) (source, error) {
+		// The tar reader leaves f at the start of the entry data.
+		off, err := f.Seek(0, io.SeekCurrent)
+
+		if err != nil {
+			return source{}, err
+		}
+
+		src := source{
+			name: p + ":" + hdr.Name,
+			open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(io.NewSectionReader(f, off, hdr.Size)), nil
+			},
+		}
+
+		return src, nil
+	})
+
+	if err != nil {
+		closeFile(p, f)
+		return err
+	}
+
+	a.files = append(a.files, f)
+	return nil
+}
+
+// readTgz reads the entries of a gzip compressed tar archive. Since it can't
+// be accessed randomly, image entries are loaded in memory.
+func readTgz(p string, fn func(string, source) error) error {
+	f, err := os.Open(p)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeFile(p, f)
+	zr, err := gzip.NewReader(f)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeFile(p, zr)
+
+	return readTarEntries(zr, fn, func(tr *tar.Reader, hdr *tar.Header) (source, error) {
+		data, err := ioutil.ReadAll(tr)
+
+		if err != nil {
+			return source{}, err
+		}
+
+		src := source{
+			name: p + ":" + hdr.Name,
+			open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(data)), nil
+			},
+		}
+
+		return src, nil
+	})
+}
+
+// readTarEntries calls fn for every image entry of the tar archive in r, in
+// archive order. Their sources are returned by entry, which is called right
+// after reading the entry header.
+func readTarEntries(r io.Reader, fn func(string, source) error, entry func(*tar.Reader, *tar.Header) (source, error)) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// Sparse entries are skipped, their data isn't stored as is.
+		if !hdr.FileInfo().Mode().IsRegular() || hdr.Typeflag == tar.TypeGNUSparse || !isImage(hdr.Name) {
+			continue
+		}
+
+		src, err := entry(tr, hdr)
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(hdr.Name, src); err != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the archives, their sources can't be read after it.
+func (a *archives) Close() error {
+	var err error
+
+	for _, f := range a.files {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	a.files = nil
+	return err
+}
@@ -0,0 +1,277 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	"github.com/ntrrg/tiler/pkg/pack"
+)
+
+// runAtlas packs the given images into texture atlases, one per group, along
+// with their JSON (and optionally CSS) coordinates.
+func runAtlas(args []string) {
+	var (
+		in      inputFlags
+		verbose bool
+		padding int
+		trim    bool
+		maxSize int
+		pot     bool
+		css     bool
+		output  string
+	)
+
+	fs := flag.NewFlagSet("atlas", flag.ExitOnError)
+	in.register(fs)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	fs.IntVar(&padding, "padding", 2, "Space between frames, in pixels")
+	fs.BoolVar(&trim, "trim", false, "Trim the transparent borders of frames")
+	fs.IntVar(&maxSize, "max-size", 4096, "Maximum atlas width and height")
+	fs.BoolVar(&pot, "pot", false, "Use power of two atlas sizes")
+	fs.BoolVar(&css, "css", false, "Write a CSS sprites stylesheet")
+
+	fs.StringVar(
+		&output,
+		"o",
+		"atlas.png",
+		"Output file, {group} in file name is replaced by the subdirectory "+
+			"name (see -r), the JSON and CSS files use the same name",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	groups, err := in.groups(fs.Args())
+
+	if err != nil {
+		log.Fatalf("Can't read the given images -> %v\n", err)
+	}
+
+	var fails failures
+
+	for _, g := range groups {
+		name := outputName(output, g.name, 0)
+		err := buildAtlas(g, name, padding, trim, image.Pt(maxSize, maxSize), pot, css)
+
+		if err != nil {
+			fails.add(name, "", err)
+			continue
+		}
+
+		if verbose {
+			fmt.Printf("Atlas with %d frames written to '%s'\n", len(g.images), name)
+		}
+	}
+
+	in.close()
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// atlas is a JSON atlas, compatible with the TexturePacker hash format.
+type atlas struct {
+	Frames map[string]atlasFrame `json:"frames"`
+	Meta   atlasMeta             `json:"meta"`
+}
+
+type atlasFrame struct {
+	Frame            rect      `json:"frame"`
+	Trimmed          bool      `json:"trimmed"`
+	SpriteSourceSize rect      `json:"spriteSourceSize"`
+	SourceSize       atlasSize `json:"sourceSize"`
+}
+
+type atlasMeta struct {
+	Image string    `json:"image"`
+	Size  atlasSize `json:"size"`
+}
+
+type atlasSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// buildAtlas packs the images of g into the atlas with the given name.
+func buildAtlas(g *group, name string, padding int, trim bool, max image.Point, pot, css bool) error {
+	imgs := make([]image.Image, len(g.images))
+	areas := make([]image.Rectangle, len(g.images))
+	sizes := make([]image.Point, len(g.images))
+
+	for i, src := range g.images {
+		img, err := src.decode()
+
+		if err != nil {
+			return fmt.Errorf("'%s': %v", src.name, err)
+		}
+
+		imgs[i] = img
+		areas[i] = img.Bounds()
+
+		if trim {
+			areas[i] = opaqueBounds(img)
+		}
+
+		sizes[i] = areas[i].Size().Add(image.Pt(padding, padding))
+	}
+
+	// The padding is added to every frame and trimmed from the atlas edges
+	// later, so it doesn't count in the maximum size. Power of two atlases
+	// keep it, so it does for them.
+	limit := max
+
+	if !pot {
+		limit = max.Add(image.Pt(padding, padding))
+	}
+
+	pos, size, err := pack.Pack(sizes, limit, pot)
+
+	if err != nil {
+		return err
+	}
+
+	if !pot {
+		size = size.Sub(image.Pt(padding, padding))
+	}
+
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+
+	a := atlas{
+		Frames: make(map[string]atlasFrame, len(imgs)),
+		Meta: atlasMeta{
+			Image: path.Base(strings.Replace(name, "\\", "/", -1)),
+			Size:  atlasSize{size.X, size.Y},
+		},
+	}
+
+	for i, img := range imgs {
+		r := image.Rectangle{pos[i], pos[i].Add(areas[i].Size())}
+		draw.Draw(dst, r, img, areas[i].Min, draw.Src)
+		b := img.Bounds()
+
+		a.Frames[g.images[i].name] = atlasFrame{
+			Frame:   newRect(r),
+			Trimmed: areas[i] != b,
+			SpriteSourceSize: newRect(image.Rectangle{
+				areas[i].Min.Sub(b.Min),
+				areas[i].Max.Sub(b.Min),
+			}),
+			SourceSize: atlasSize{b.Dx(), b.Dy()},
+		}
+	}
+
+	if err := writeImage(dirSink{}, name, dst); err != nil {
+		return err
+	}
+
+	if err := writeManifest(dirSink{}, manifestName(name), a); err != nil {
+		return err
+	}
+
+	if css {
+		p := strings.TrimSuffix(name, path.Ext(name)) + ".css"
+
+		return writeDescriptor(p, func(w io.Writer) error {
+			return writeCSS(w, a)
+		})
+	}
+
+	return nil
+}
+
+// opaqueBounds returns the smallest area of img that contains all its non
+// transparent pixels.
+func opaqueBounds(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	r := image.Rectangle{Min: b.Max, Max: b.Min}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a == 0 {
+				continue
+			}
+
+			if x < r.Min.X {
+				r.Min.X = x
+			}
+
+			if y < r.Min.Y {
+				r.Min.Y = y
+			}
+
+			if x >= r.Max.X {
+				r.Max.X = x + 1
+			}
+
+			if y >= r.Max.Y {
+				r.Max.Y = y + 1
+			}
+		}
+	}
+
+	if r.Empty() {
+		return image.Rectangle{b.Min, b.Min}
+	}
+
+	return r
+}
+
+// cssClass matches the characters that are not allowed in CSS class names.
+var cssClass = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// writeCSS writes a CSS sprites stylesheet for a.
+func writeCSS(w io.Writer, a atlas) error {
+	names := make([]string, 0, len(a.Frames))
+
+	for name := range a.Frames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	classes := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		f := a.Frames[name]
+		base := path.Base(strings.Replace(name, "\\", "/", -1))
+		class := cssClass.ReplaceAllString(strings.TrimSuffix(base, path.Ext(base)), "-")
+
+		// Images from different directories may have the same base name.
+		for i, c := 2, class; classes[class]; i++ {
+			class = fmt.Sprintf("%s-%d", c, i)
+		}
+
+		classes[class] = true
+
+		_, err := fmt.Fprintf(
+			w,
+			".sprite-%s {\n  background: url(%s) -%dpx -%dpx;\n  width: %dpx;\n  height: %dpx;\n}\n\n",
+			class,
+			a.Meta.Image,
+			f.Frame.X,
+			f.Frame.Y,
+			f.Frame.W,
+			f.Frame.H,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
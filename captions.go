@@ -0,0 +1,246 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/sfnt"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// CaptionDateLayout is the layout of the image dates used as captions.
+const CaptionDateLayout = "2006-01-02 15:04"
+
+// captionOptions are the options for drawing a caption under every tile.
+type captionOptions struct {
+	mode  string
+	texts string
+	size  string
+	color string
+	pos   string
+	font  string
+
+	// Set by load.
+	typeface *sfnt.Font
+	px       float64
+	height   int
+	index    map[string]string
+}
+
+func (o *captionOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(
+		&o.mode,
+		"caption",
+		"",
+		"Caption every tile with its 'filename', 'index', 'date' (from EXIF) "+
+			"or 'text' (see -captions)",
+	)
+
+	fs.StringVar(
+		&o.texts,
+		"captions",
+		"",
+		"With -caption text, file with a line per image, its path (or file "+
+			"name), a tab and its caption",
+	)
+
+	fs.StringVar(&o.size, "caption-size", "10pt", "Caption font size (px, in, cm, mm or pt)")
+	fs.StringVar(&o.color, "caption-color", "black", "Caption text color")
+
+	fs.StringVar(
+		&o.pos,
+		"caption-pos",
+		"below",
+		"Caption placement, 'below' shrinks the images to leave room for "+
+			"the captions, 'over' draws them over the bottom of the images",
+	)
+
+	fs.StringVar(
+		&o.font,
+		"font",
+		"",
+		"Caption TrueType or OpenType font file, the bundled Go font is used "+
+			"if empty",
+	)
+}
+
+// enabled reports whether captions should be drawn.
+func (o *captionOptions) enabled() bool {
+	return o.mode != ""
+}
+
+// load validates o and loads the caption font and texts for sheets with the
+// given resolution.
+func (o *captionOptions) load(dpi int) error {
+	switch o.mode {
+	case "filename", "index", "date":
+	case "text":
+		if o.texts == "" {
+			return fmt.Errorf("-caption text requires -captions")
+		}
+
+		index, err := readCaptions(o.texts)
+
+		if err != nil {
+			return err
+		}
+
+		o.index = index
+	default:
+		return fmt.Errorf("unknown caption '%s'", o.mode)
+	}
+
+	if o.pos != "below" && o.pos != "over" {
+		return fmt.Errorf("unknown caption placement '%s'", o.pos)
+	}
+
+	if _, ok := colornames.Map[o.color]; !ok {
+		return fmt.Errorf("unknown caption color '%s'", o.color)
+	}
+
+	px, err := parseLength(o.size, dpi)
+
+	if err != nil {
+		return err
+	}
+
+	if px < 1 {
+		return fmt.Errorf("invalid caption size '%s'", o.size)
+	}
+
+	data := tile.DefaultFont
+
+	if o.font != "" {
+		if data, err = ioutil.ReadFile(filepath.Clean(o.font)); err != nil {
+			return err
+		}
+	}
+
+	if o.typeface, err = tile.ParseFont(data); err != nil {
+		return fmt.Errorf("can't parse the font '%s' -> %v", o.font, err)
+	}
+
+	o.px = float64(px)
+	o.height = o.caption(nil).Height()
+	return nil
+}
+
+// reserve returns the height reserved for captions in every tile.
+func (o *captionOptions) reserve() int64 {
+	if o.pos == "over" {
+		return 0
+	}
+
+	return int64(o.height)
+}
+
+// caption returns a new caption with its own face, so it may be used
+// concurrently with other captions. Captions over images have a translucent
+// bg background.
+func (o *captionOptions) caption(bg color.Color) *tile.Caption {
+	c := &tile.Caption{Face: tile.NewFace(o.typeface, o.px), Color: colornames.Map[o.color]}
+
+	if o.pos == "over" && bg != nil {
+		r, g, b, _ := bg.RGBA()
+		c.Background = color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xb0}
+		c.Over = true
+	}
+
+	return c
+}
+
+// text returns the caption of img, n is its position in its group and date
+// the date read from its metadata.
+func (o *captionOptions) text(img source, n int64, date time.Time) string {
+	switch o.mode {
+	case "filename":
+		return path.Base(filepath.ToSlash(img.name))
+	case "index":
+		return strconv.FormatInt(n+1, 10)
+	case "date":
+		if date.IsZero() {
+			return ""
+		}
+
+		return date.Format(CaptionDateLayout)
+	case "text":
+		if s, ok := o.index[img.name]; ok {
+			return s
+		}
+
+		return o.index[path.Base(filepath.ToSlash(img.name))]
+	}
+
+	return ""
+}
+
+// drawCaptions draws the captions of the images of s into dst, using their
+// placements from layout and their dates, if any, indexed like the images.
+// Placeholders of images that couldn't be drawn are captioned too.
+func drawCaptions(dst *tile.Tiler, s sheet, layout sheetLayout, dates []time.Time, f *tile.Format, bg color.Color, o *captionOptions) {
+	c := o.caption(bg)
+
+	for i, img := range s.images {
+		if img.blank() {
+			continue
+		}
+
+		sl := layout.Slots[i]
+		p := tile.Placement{Tile: sl.Tile.Rectangle(), Dst: sl.Dest.Rectangle()}
+
+		if sl.Error != "" {
+			t, _ := f.Format(dst.Tile(int64(i)), nil)
+			p = tile.Placement{Tile: t, Dst: t}
+		}
+
+		var date time.Time
+
+		if dates != nil {
+			date = dates[i]
+		}
+
+		c.Draw(dst, p, o.text(img, s.first+int64(i), date))
+	}
+}
+
+// readCaptions returns the captions listed in the file at p, indexed by
+// image path. Every line has an image path, a tab and its caption.
+func readCaptions(p string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(p))
+
+	if err != nil {
+		return nil, err
+	}
+
+	captions := make(map[string]string)
+
+	for i, l := range bytes.Split(data, []byte("\n")) {
+		l = bytes.TrimSuffix(l, []byte("\r"))
+
+		if len(l) == 0 {
+			continue
+		}
+
+		parts := bytes.SplitN(l, []byte("\t"), 2)
+
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: missing tab after the image path", p, i+1)
+		}
+
+		captions[string(parts[0])] = string(parts[1])
+	}
+
+	return captions, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadCaptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiler")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name string
+		data string
+		want map[string]string
+		err  bool
+	}{
+		{"empty", "", map[string]string{}, false},
+		{
+			"lines",
+			"a.jpg\tFirst\nphotos/b.jpg\tSecond one\n",
+			map[string]string{"a.jpg": "First", "photos/b.jpg": "Second one"},
+			false,
+		},
+		{
+			"crlf and blank lines",
+			"a.jpg\tFirst\r\n\r\n\nb.jpg\tSecond\r\n",
+			map[string]string{"a.jpg": "First", "b.jpg": "Second"},
+			false,
+		},
+		{
+			"tabs in captions",
+			"a.jpg\tOne\tTwo\nb.jpg\t\n",
+			map[string]string{"a.jpg": "One\tTwo", "b.jpg": ""},
+			false,
+		},
+		{
+			"repeated images",
+			"a.jpg\tFirst\na.jpg\tLast",
+			map[string]string{"a.jpg": "Last"},
+			false,
+		},
+		{"missing tab", "a.jpg\tFirst\nb.jpg Second\n", nil, true},
+	}
+
+	for i, c := range cases {
+		p := filepath.Join(dir, string('a'+rune(i))+".tsv")
+
+		if err := ioutil.WriteFile(p, []byte(c.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := readCaptions(p)
+
+		if (err != nil) != c.err {
+			t.Errorf("%s: got error %v, want error %v", c.name, err, c.err)
+			continue
+		}
+
+		if err == nil && !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	if _, err := readCaptions(filepath.Join(dir, "missing.tsv")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ntrrg/tiler/pkg/exif"
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// runCheck validates the given images without composing them, and exits with
+// a non-zero status if any problem is found.
+func runCheck(args []string) {
+	var (
+		in         inputFlags
+		tiles      int64
+		size       string
+		maxUpscale float64
+
+		format = *tile.DefaultFormat
+	)
+
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	in.register(fs)
+	fs.Int64Var(&tiles, "tiles", 4, "Number of tiles, at least 2")
+	fs.StringVar(&size, "size", "letter300", "Output file size")
+	fs.StringVar(&format.Resize, "resize", "contain", "Resizing mode")
+	fs.Int64Var(&format.Margin, "margin", 0, "Margin")
+
+	fs.Float64Var(
+		&maxUpscale,
+		"max-upscale",
+		2,
+		"Report images that would be scaled up more than this factor",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	groups, err := in.groups(fs.Args())
+
+	if err != nil {
+		log.Fatalf("Can't read the given images -> %v\n", err)
+	}
+
+	s, ok := OutputSizes[size]
+
+	if !ok {
+		log.Fatalf("Invalid output size '%s'\n", size)
+	}
+
+	if tiles < 1 {
+		log.Fatalf("Invalid tiles number %d\n", tiles)
+	}
+
+	cell, _ := format.Format(tile.Grid(s, tiles)[0], nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tFORMAT\tSIZE\tIMAGE\tPROBLEMS")
+
+	seen := make(map[string]string)
+	n, bad := 0, 0
+
+	for _, g := range groups {
+		for _, img := range g.images {
+			n++
+			r := checkImage(img, cell, format.Resize, maxUpscale)
+
+			key, err := filepath.Abs(img.name)
+
+			if err != nil {
+				key = img.name
+			}
+
+			if prev, ok := seen[key]; ok {
+				r.problems = append(r.problems, fmt.Sprintf("duplicate of '%s'", prev))
+			} else {
+				seen[key] = img.name
+			}
+
+			status, problems := "ok", "-"
+
+			if len(r.problems) > 0 {
+				status, problems = "FAIL", strings.Join(r.problems, "; ")
+				bad++
+			}
+
+			fmt.Fprintf(
+				tw,
+				"%s\t%s\t%s\t%s\t%s\n",
+				status,
+				r.format,
+				r.size,
+				img.name,
+				problems,
+			)
+		}
+	}
+
+	tw.Flush()
+	in.close()
+	fmt.Printf("\n%d images checked, %d with problems\n", n, bad)
+
+	if bad > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkResult is the outcome of checking an image.
+type checkResult struct {
+	format   string
+	size     string
+	problems []string
+}
+
+// checkImage validates img by decoding its header, it is expected to be drawn
+// in a tile of cell size using the given resize mode.
+func checkImage(img source, cell image.Rectangle, resize string, maxUpscale float64) checkResult {
+	r := checkResult{format: "-", size: "-"}
+	f, err := img.open()
+
+	if err != nil {
+		r.problems = append(r.problems, err.Error())
+		return r
+	}
+
+	cfg, format, err := image.DecodeConfig(f)
+	closeFile(img.name, f)
+
+	if err != nil {
+		if err == image.ErrFormat {
+			err = fmt.Errorf("unsupported format")
+		}
+
+		r.problems = append(r.problems, err.Error())
+		return r
+	}
+
+	r.format = format
+	r.size = fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)
+
+	if cfg.Width == 0 || cfg.Height == 0 {
+		r.problems = append(r.problems, "empty image")
+		return r
+	}
+
+	bounds := image.Rect(0, 0, cfg.Width, cfg.Height)
+
+	if c := tile.ScaleFactor(bounds, cell, resize); c > maxUpscale {
+		r.problems = append(r.problems, fmt.Sprintf("too small, scaled up %.1fx", c))
+	}
+
+	if format == "jpeg" {
+		if f, err = img.open(); err == nil {
+			x, err := exif.Decode(f)
+			closeFile(img.name, f)
+
+			if err == nil && x.Orientation > 1 {
+				r.problems = append(
+					r.problems,
+					fmt.Sprintf("EXIF orientation %d is not applied", x.Orientation),
+				)
+			}
+		}
+	}
+
+	return r
+}
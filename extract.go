@@ -0,0 +1,138 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/colornames"
+
+	"github.com/ntrrg/tiler/pkg/scan"
+)
+
+// runExtract detects the photos in the given flatbed scans and writes each
+// one to its own file.
+func runExtract(args []string) {
+	var (
+		verbose bool
+		bg      string
+		output  string
+
+		opts = *scan.DefaultOptions
+	)
+
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+
+	fs.StringVar(
+		&bg,
+		"bg",
+		"auto",
+		"Scanner background color, 'auto' estimates it from the scan borders",
+	)
+
+	fs.Float64Var(
+		&opts.Threshold,
+		"threshold",
+		opts.Threshold,
+		"Minimum color distance (0-1) from the background for photo pixels",
+	)
+
+	fs.Float64Var(
+		&opts.MinArea,
+		"min-area",
+		opts.MinArea,
+		"Minimum photo area, relative to the scan area",
+	)
+
+	fs.Float64Var(
+		&opts.MaxAngle,
+		"max-angle",
+		opts.MaxAngle,
+		"Maximum rotation (in degrees) corrected",
+	)
+
+	fs.StringVar(
+		&output,
+		"o",
+		"{scan}-%d.jpg",
+		"Output file, %d in file name is replaced by photo number and {scan} "+
+			"by the scan name",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 1 {
+		log.Fatalln("At least 1 scan should be given")
+	}
+
+	if bg != "auto" {
+		c, ok := colornames.Map[bg]
+
+		if !ok {
+			log.Fatalf("Unknown background color '%s'\n", bg)
+		}
+
+		opts.Background = c
+	}
+
+	var fails failures
+
+	for _, p := range fs.Args() {
+		img, err := fileSource(p).decode()
+
+		if err != nil {
+			fails.add("", p, err)
+			continue
+		}
+
+		regions, err := scan.Detect(img, &opts)
+
+		if err != nil {
+			fails.add("", p, err)
+			continue
+		}
+
+		if len(regions) == 0 {
+			fails.add("", p, fmt.Errorf("no photos found"))
+			continue
+		}
+
+		vars := map[string]string{
+			"scan": strings.TrimSuffix(p, filepath.Ext(p)),
+		}
+
+		for i, r := range regions {
+			name := expandName(output, vars, int64(i))
+
+			if verbose {
+				fmt.Printf(
+					"%s: photo #%d at %v, %dx%d rotated %.2f° -> '%s'\n",
+					p,
+					i,
+					r.Bounds,
+					r.Width,
+					r.Height,
+					r.Angle,
+					name,
+				)
+			}
+
+			if err := writeImage(dirSink{}, name, scan.Extract(img, r)); err != nil {
+				fails.add(name, p, err)
+			}
+		}
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
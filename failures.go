@@ -0,0 +1,62 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// failure is an error found while processing an input image or an output
+// file.
+type failure struct {
+	output string
+	input  string
+	err    error
+}
+
+func (f failure) String() string {
+	switch {
+	case f.output == "":
+		return fmt.Sprintf("'%s': %v", f.input, f.err)
+	case f.input == "":
+		return fmt.Sprintf("'%s': %v", f.output, f.err)
+	}
+
+	return fmt.Sprintf("'%s' (at '%s'): %v", f.input, f.output, f.err)
+}
+
+// failures collects the errors found during a run, it is safe for concurrent
+// use.
+type failures struct {
+	mu   sync.Mutex
+	list []failure
+}
+
+// add records an error related to the output file and/or the input image.
+func (fs *failures) add(output, input string, err error) {
+	fs.mu.Lock()
+	fs.list = append(fs.list, failure{output: output, input: input, err: err})
+	fs.mu.Unlock()
+}
+
+// len returns the number of recorded errors.
+func (fs *failures) len() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return len(fs.list)
+}
+
+// report writes a summary of the recorded errors to w.
+func (fs *failures) report(w io.Writer) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fmt.Fprintf(w, "%d error(s) found:\n", len(fs.list))
+
+	for _, f := range fs.list {
+		fmt.Fprintf(w, "  %v\n", f)
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/colornames"
+
+	"github.com/ntrrg/tiler/pkg/anim"
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// runExplode composes the frames of the given animated GIFs into sprite
+// sheets.
+func runExplode(args []string) {
+	var (
+		verbose  bool
+		bg       string
+		manifest bool
+		output   string
+	)
+
+	fs := flag.NewFlagSet("explode", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	fs.StringVar(&bg, "bg", "", "Sheet background color, transparent if empty")
+
+	fs.BoolVar(
+		&manifest,
+		"manifest",
+		false,
+		"Write a layout manifest next to every sheet, it can be used by "+
+			"'animate' and 'split'",
+	)
+
+	fs.StringVar(
+		&output,
+		"o",
+		"{gif}.png",
+		"Output file, {gif} in file name is replaced by the GIF name",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 1 {
+		log.Fatalln("At least 1 GIF should be given")
+	}
+
+	var c color.Color = color.Transparent
+
+	if bg != "" {
+		c = colornames.Map[bg]
+	}
+
+	var fails failures
+
+	for _, p := range fs.Args() {
+		vars := map[string]string{"gif": strings.TrimSuffix(p, filepath.Ext(p))}
+		name := expandName(output, vars, 0)
+		n, err := explodeGIF(p, name, c, manifest)
+
+		if err != nil {
+			fails.add(name, p, err)
+			continue
+		}
+
+		if verbose {
+			fmt.Printf("%d frames of '%s' written to '%s'\n", n, p, name)
+		}
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// explodeGIF writes the frames of the GIF at p into the sheet with the given
+// name and returns the number of frames.
+func explodeGIF(p, name string, bg color.Color, manifest bool) (int, error) {
+	f, err := os.Open(filepath.Clean(p))
+
+	if err != nil {
+		return 0, err
+	}
+
+	g, err := gif.DecodeAll(f)
+	closeFile(p, f)
+
+	if err != nil {
+		return 0, err
+	}
+
+	frames := anim.Frames(g)
+
+	if len(frames) == 0 {
+		return 0, fmt.Errorf("the GIF has no frames")
+	}
+
+	fsize := frames[0].Bounds().Size()
+	t := tile.New(bg, frameSheet(fsize, int64(len(frames))), int64(len(frames)))
+	format := &tile.Format{Resize: "none"}
+	layout := sheetLayout{Output: name, Group: p}
+
+	for i, frame := range frames {
+		pl := t.DrawImageAt(frame, int64(i), format)
+
+		layout.Slots = append(layout.Slots, slotLayout{
+			Slot:   int64(i),
+			Image:  fmt.Sprintf("%s#%d", p, i),
+			Width:  fsize.X,
+			Height: fsize.Y,
+			Cell:   newRect(t.Tile(int64(i))),
+			Tile:   newRect(pl.Tile),
+			Crop:   newRect(pl.Src),
+			Dest:   newRect(pl.Dst),
+			Scale:  pl.Scale,
+		})
+	}
+
+	b := t.Bounds()
+	layout.Width, layout.Height = b.Dx(), b.Dy()
+
+	if err := writeImage(dirSink{}, name, t); err != nil {
+		return 0, err
+	}
+
+	if manifest {
+		if err := writeManifest(dirSink{}, manifestName(name), layout); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(frames), nil
+}
+
+// frameSheet returns the most square sheet whose grid of n tiles (see
+// tile.Grid) has tiles of exactly the frame size.
+func frameSheet(frame image.Point, n int64) image.Rectangle {
+	if n < 4 {
+		n = 4
+	}
+
+	if n%2 != 0 {
+		n++
+	}
+
+	var best image.Rectangle
+
+	for c := int64(1); c <= n; c++ {
+		if n%c != 0 {
+			continue
+		}
+
+		r := image.Rect(0, 0, int(c)*frame.X, int(n/c)*frame.Y)
+
+		if tile.Grid(r, n)[0].Size() != frame {
+			continue
+		}
+
+		if best.Empty() || squareness(r) < squareness(best) {
+			best = r
+		}
+	}
+
+	return best
+}
+
+// squareness returns how far from a square r is, 0 for squares.
+func squareness(r image.Rectangle) int {
+	d := r.Dx() - r.Dy()
+
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
+
+// runAnimate builds animated GIFs from the tiles of the given sprite sheets.
+func runAnimate(args []string) {
+	var (
+		verbose  bool
+		manifest string
+		tiles    int64
+		frames   int
+		output   string
+
+		opts = anim.Options{Delay: 10, Palette: anim.Adaptive}
+	)
+
+	fs := flag.NewFlagSet("animate", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+
+	fs.StringVar(
+		&manifest,
+		"manifest",
+		"",
+		"Layout manifest of the sheets, if not given, the grid spec is used",
+	)
+
+	fs.Int64Var(&tiles, "tiles", 4, "Number of tiles of the grid spec")
+
+	fs.IntVar(
+		&frames,
+		"frames",
+		0,
+		"Number of frames, all the tiles are used if 0",
+	)
+
+	fs.IntVar(&opts.Delay, "delay", opts.Delay, "Delay between frames, in hundredths of a second")
+	fs.IntVar(&opts.LoopCount, "loop", 0, "Number of repetitions, 0 is forever and -1 is once")
+
+	fs.StringVar(
+		&opts.Palette,
+		"palette",
+		opts.Palette,
+		"Color palette, 'adaptive', 'plan9' or 'websafe'",
+	)
+
+	fs.BoolVar(&opts.Dither, "dither", false, "Use Floyd-Steinberg dithering")
+
+	fs.StringVar(
+		&output,
+		"o",
+		"{sheet}.gif",
+		"Output file, {sheet} in file name is replaced by the sheet name",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 1 {
+		log.Fatalln("At least 1 sheet should be given")
+	}
+
+	switch opts.Palette {
+	case anim.Adaptive, anim.Plan9, anim.WebSafe:
+	default:
+		log.Fatalf("Unknown palette '%s'\n", opts.Palette)
+	}
+
+	var layouts []sheetLayout
+
+	if manifest != "" {
+		var err error
+		layouts, err = readManifest(manifest)
+
+		if err != nil {
+			log.Fatalf("Can't read the manifest -> %v\n", err)
+		}
+	}
+
+	var fails failures
+
+	for _, p := range fs.Args() {
+		vars := map[string]string{"sheet": strings.TrimSuffix(p, filepath.Ext(p))}
+		name := expandName(output, vars, 0)
+		n, err := animateSheet(p, name, layouts, tiles, frames, &opts)
+
+		if err != nil {
+			fails.add(name, p, err)
+			continue
+		}
+
+		if verbose {
+			fmt.Printf("%d frames of '%s' written to '%s'\n", n, p, name)
+		}
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// animateSheet writes the tiles of the sheet at p as the frames of the GIF
+// with the given name and returns the number of frames. If layouts is empty,
+// tiles are computed from the grid spec.
+func animateSheet(p, name string, layouts []sheetLayout, tiles int64, n int, o *anim.Options) (int, error) {
+	src, err := fileSource(p).decode()
+
+	if err != nil {
+		return 0, err
+	}
+
+	var cells []image.Rectangle
+	sb := src.Bounds()
+
+	if len(layouts) > 0 {
+		l, ok := findLayout(layouts, p)
+
+		if !ok {
+			return 0, fmt.Errorf("not found in the manifest")
+		}
+
+		sx, sy := 1.0, 1.0
+
+		if l.Width > 0 && l.Height > 0 {
+			sx = float64(sb.Dx()) / float64(l.Width)
+			sy = float64(sb.Dy()) / float64(l.Height)
+		}
+
+		for _, s := range l.Slots {
+			r := s.Cell.Rectangle()
+
+			cells = append(cells, image.Rect(
+				int(float64(r.Min.X)*sx),
+				int(float64(r.Min.Y)*sy),
+				int(float64(r.Max.X)*sx),
+				int(float64(r.Max.Y)*sy),
+			).Add(sb.Min))
+		}
+	} else {
+		cells = tile.Grid(sb, tiles)
+	}
+
+	if n > 0 && n < len(cells) {
+		cells = cells[:n]
+	}
+
+	if len(cells) == 0 {
+		return 0, fmt.Errorf("the sheet has no frames")
+	}
+
+	frames := make([]image.Image, len(cells))
+
+	for i, r := range cells {
+		frames[i] = crop(src, r)
+	}
+
+	g := anim.New(frames, o)
+
+	err = writeDescriptor(name, func(w io.Writer) error {
+		return gif.EncodeAll(w, g)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(frames), nil
+}
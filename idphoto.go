@@ -0,0 +1,336 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/draw"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// IDFormats is a set of common ID photo formats.
+var IDFormats = map[string]idFormat{
+	// ICAO passports (EU, UK and others), head of 32 to 36 mm.
+	"35x45mm": {"35x45mm", 0.75},
+
+	// US passports and visas, head of 1 to 1 3/8 in.
+	"2x2in": {"2x2in", 0.6},
+
+	// Canadian passports, face of 31 to 36 mm.
+	"50x70mm": {"50x70mm", 0.48},
+}
+
+// idFormat is an ID photo specification.
+type idFormat struct {
+	// size is the photo size, with a physical unit (see parseSize).
+	size string
+
+	// faceHeight is the height of the face, from chin to crown, relative to
+	// the photo height.
+	faceHeight float64
+}
+
+// runIDPhoto crops the given images to an ID photo size and fills sheets with
+// as many copies as fit.
+func runIDPhoto(args []string) {
+	var (
+		verbose    bool
+		format     string
+		size       string
+		bg         string
+		margin     string
+		gap        string
+		copies     int
+		face       string
+		faceHeight float64
+		cutLines   bool
+		output     string
+	)
+
+	fs := flag.NewFlagSet("idphoto", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+
+	fs.StringVar(
+		&format,
+		"format",
+		"35x45mm",
+		"Photo size, '35x45mm', '2x2in', '50x70mm' or any WxH size with a "+
+			"physical unit",
+	)
+
+	fs.StringVar(&size, "size", "4x6_300", "Sheet size")
+	fs.StringVar(&bg, "bg", "white", "Sheet background color")
+	fs.StringVar(&margin, "margin", "3mm", "Sheet margin (px, in, cm, mm or pt)")
+	fs.StringVar(&gap, "gap", "2mm", "Space between photos (px, in, cm, mm or pt)")
+
+	fs.IntVar(
+		&copies,
+		"copies",
+		0,
+		"Copies of every image, 0 fills a sheet per image",
+	)
+
+	fs.StringVar(
+		&face,
+		"face",
+		"",
+		"Face box as X,Y,W,H in image pixels, the photo is centered on it",
+	)
+
+	fs.Float64Var(
+		&faceHeight,
+		"face-height",
+		0,
+		"With -face, height of the face box relative to the photo height, "+
+			"if 0, the -format one is used (0.6 for custom sizes)",
+	)
+
+	fs.BoolVar(&cutLines, "cut-lines", true, "Draw cut lines around the photos")
+	fs.StringVar(&output, "o", "idphoto%d.jpg", "Output file, %d in file name is replaced by sheet number")
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 1 {
+		log.Fatalln("At least 1 image should be given")
+	}
+
+	// The face box is in image pixels, so it only makes sense for one image.
+	if face != "" && fs.NArg() > 1 {
+		log.Fatalln("-face can only be used with a single image")
+	}
+
+	page, ok := OutputSizes[size]
+
+	if !ok {
+		log.Fatalf("Unknown sheet size '%s'\n", size)
+	}
+
+	dpi := OutputDPI[size]
+
+	spec, ok := IDFormats[format]
+
+	if !ok {
+		spec = idFormat{size: format, faceHeight: 0.6}
+	}
+
+	if faceHeight <= 0 {
+		faceHeight = spec.faceHeight
+	}
+
+	photo, err := parseSize(spec.size, dpi)
+
+	if err != nil {
+		log.Fatalf("Invalid photo size -> %v\n", err)
+	}
+
+	m, err := parseLength(margin, dpi)
+
+	if err != nil {
+		log.Fatalf("Invalid margin -> %v\n", err)
+	}
+
+	g, err := parseLength(gap, dpi)
+
+	if err != nil {
+		log.Fatalf("Invalid gap -> %v\n", err)
+	}
+
+	var box image.Rectangle
+
+	if face != "" {
+		var x, y, w, h int
+
+		if _, err := fmt.Sscanf(face, "%d,%d,%d,%d", &x, &y, &w, &h); err != nil || w < 1 || h < 1 {
+			log.Fatalf("Invalid face box '%s'\n", face)
+		}
+
+		box = image.Rect(x, y, x+w, y+h)
+	}
+
+	cells := photoCells(page.Inset(m), photo, g)
+
+	if len(cells) == 0 {
+		log.Fatalln("The photo doesn't fit in the sheet")
+	}
+
+	n := copies
+
+	if n < 1 {
+		n = len(cells)
+	}
+
+	if verbose {
+		fmt.Printf(
+			"%d photos of %dx%d px per sheet, %d copies of every image\n",
+			len(cells),
+			photo.X,
+			photo.Y,
+			n,
+		)
+	}
+
+	var (
+		fails  failures
+		dst    *tile.Tiler
+		sheets int64
+		off    int
+	)
+
+	flush := func() {
+		if dst == nil {
+			return
+		}
+
+		if cutLines {
+			drawCutLines(dst, page, cells[:off], dpi)
+		}
+
+		name := expandName(output, nil, sheets)
+
+		if err := writeImage(dirSink{}, name, dst); err != nil {
+			fails.add(name, "", err)
+		}
+
+		dst, off = nil, 0
+		sheets++
+	}
+
+	for _, p := range fs.Args() {
+		src, err := fileSource(p).decode()
+
+		if err != nil {
+			fails.add("", p, err)
+			continue
+		}
+
+		img := cropPhoto(src, photo, box, faceHeight)
+
+		for i := 0; i < n; i++ {
+			if dst == nil {
+				dst = tile.NewWithTiles(colornames.Map[bg], page, cells)
+			}
+
+			dst.DrawImageAt(img, int64(off), &tile.Format{Resize: "none"})
+			off++
+
+			if off == len(cells) {
+				flush()
+			}
+		}
+	}
+
+	flush()
+
+	if verbose {
+		fmt.Printf("%d sheets written\n", sheets)
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// photoCells returns the areas of as many photos of the given size as fit in
+// r, with gap space between them. They are centered in r and ordered by row.
+func photoCells(r image.Rectangle, photo image.Point, gap int) []image.Rectangle {
+	if photo.X < 1 || photo.Y < 1 {
+		return nil
+	}
+
+	cols := (r.Dx() + gap) / (photo.X + gap)
+	rows := (r.Dy() + gap) / (photo.Y + gap)
+
+	if cols < 1 || rows < 1 {
+		return nil
+	}
+
+	o := r.Min.Add(image.Pt(
+		(r.Dx()-cols*(photo.X+gap)+gap)/2,
+		(r.Dy()-rows*(photo.Y+gap)+gap)/2,
+	))
+
+	cells := make([]image.Rectangle, 0, cols*rows)
+
+	for l := 0; l < rows; l++ {
+		for c := 0; c < cols; c++ {
+			p := o.Add(image.Pt(c*(photo.X+gap), l*(photo.Y+gap)))
+			cells = append(cells, image.Rectangle{p, p.Add(photo)})
+		}
+	}
+
+	return cells
+}
+
+// cropPhoto returns img cropped and scaled to the photo size. If face isn't
+// empty, the crop is centered on it and scaled so the face takes faceHeight of
+// the photo height, otherwise the biggest centered area is used.
+func cropPhoto(img image.Image, photo image.Point, face image.Rectangle, faceHeight float64) image.Image {
+	b := img.Bounds()
+	aspect := float64(photo.X) / float64(photo.Y)
+
+	// Biggest crop with the photo aspect ratio.
+	cw, ch := float64(b.Dx()), float64(b.Dx())/aspect
+
+	if ch > float64(b.Dy()) {
+		cw, ch = float64(b.Dy())*aspect, float64(b.Dy())
+	}
+
+	cx, cy := float64(b.Min.X)+float64(b.Dx())/2, float64(b.Min.Y)+float64(b.Dy())/2
+
+	if !face.Empty() && faceHeight > 0 {
+		if h := float64(face.Dy()) / faceHeight; h < ch {
+			cw, ch = h*aspect, h
+		}
+
+		// The face is slightly above the photo center.
+		cx = float64(face.Min.X) + float64(face.Dx())/2
+		cy = float64(face.Min.Y) + float64(face.Dy())/2 + 0.05*ch
+	}
+
+	x := clamp(cx-cw/2, float64(b.Min.X), float64(b.Max.X)-cw)
+	y := clamp(cy-ch/2, float64(b.Min.Y), float64(b.Max.Y)-ch)
+	sr := image.Rect(int(x+0.5), int(y+0.5), int(x+cw+0.5), int(y+ch+0.5)).Intersect(b)
+
+	dst := image.NewRGBA(image.Rectangle{Max: photo})
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, sr, draw.Src, nil)
+	return dst
+}
+
+// drawCutLines draws thin lines along the edges of cells, across the whole
+// page.
+func drawCutLines(dst draw.Image, page image.Rectangle, cells []image.Rectangle, dpi int) {
+	xs := make(map[int]bool)
+	ys := make(map[int]bool)
+
+	for _, c := range cells {
+		xs[c.Min.X], xs[c.Max.X] = true, true
+		ys[c.Min.Y], ys[c.Max.Y] = true, true
+	}
+
+	w := dpi / 300
+
+	for x := range xs {
+		tile.DrawLine(dst, image.Pt(x, page.Min.Y), image.Pt(x, page.Max.Y), w, colornames.Gray, 0)
+	}
+
+	for y := range ys {
+		tile.DrawLine(dst, image.Pt(page.Min.X, y), image.Pt(page.Max.X, y), w, colornames.Gray, 0)
+	}
+}
+
+// clamp returns v limited to min and max. If min is greater than max, min is
+// returned.
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(v, max))
+}
@@ -0,0 +1,555 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ntrrg/tiler/pkg/exif"
+)
+
+// ImageExts is the set of file extensions recognized as images when walking
+// directories.
+var ImageExts = map[string]bool{
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+	".webp": true,
+}
+
+// isImage reports whether the file at p is an image according to its
+// extension.
+func isImage(p string) bool {
+	return ImageExts[strings.ToLower(filepath.Ext(p))]
+}
+
+// source is an image that can be opened for reading, either from the file
+// system or from an archive.
+type source struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// fileSource returns a source for the image at p.
+func fileSource(p string) source {
+	return source{
+		name: p,
+		open: func() (io.ReadCloser, error) { return os.Open(filepath.Clean(p)) },
+	}
+}
+
+// check returns an error if s can't be read as an image. The whole image is
+// decoded, so truncated or corrupted image data is detected too.
+func (s source) check() error {
+	if s.blank() {
+		return nil
+	}
+
+	_, err := s.decode()
+	return err
+}
+
+// checkImages checks the given images (see source.check), up to jobs at once,
+// and returns their errors, indexed like images.
+func checkImages(images []source, jobs int) []error {
+	errs := make([]error, len(images))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				errs[i] = images[i].check()
+			}
+		}()
+	}
+
+	for i := range images {
+		queue <- i
+	}
+
+	close(queue)
+	wg.Wait()
+	return errs
+}
+
+// decode returns the decoded image.
+func (s source) decode() (image.Image, error) {
+	r, err := s.open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(r)
+	closeFile(s.name, r)
+	return img, err
+}
+
+// exifSize is the amount of data read ahead for finding the EXIF metadata,
+// which is stored in a segment of at most 64 KiB near the image start.
+const exifSize = 128 << 10
+
+// decodeDated is like decode, but it also returns the date of the image from
+// its EXIF metadata, or a zero time if it is not available. The metadata is
+// read along with the image, so it isn't opened twice.
+func (s source) decodeDated() (image.Image, time.Time, error) {
+	r, err := s.open()
+
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	defer closeFile(s.name, r)
+	br := bufio.NewReaderSize(r, exifSize)
+
+	// Peek doesn't consume the data, so the whole image is decoded below.
+	head, _ := br.Peek(exifSize)
+	var date time.Time
+
+	if x, err := exif.Decode(bytes.NewReader(head)); err == nil {
+		date = x.DateTime
+	}
+
+	img, _, err := image.Decode(br)
+	return img, date, err
+}
+
+// bounds returns the bounds of the image, only the image header is decoded.
+func (s source) bounds() (image.Rectangle, error) {
+	r, err := s.open()
+
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	cfg, _, err := image.DecodeConfig(r)
+	closeFile(s.name, r)
+
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	return image.Rect(0, 0, cfg.Width, cfg.Height), nil
+}
+
+// blank reports whether s is a blank page, used for padding (see
+// group.impose). Blank pages leave their tiles empty.
+func (s source) blank() bool {
+	return s.open == nil
+}
+
+func (s source) String() string {
+	return s.name
+}
+
+// inputFlags are the options for selecting and ordering input images.
+type inputFlags struct {
+	recursive bool
+	sorted    bool
+	reverse   bool
+	filesFrom string
+
+	// Set by groups.
+	archives archives
+}
+
+// register defines the input flags in fs.
+func (f *inputFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(
+		&f.reverse,
+		"reverse",
+		false,
+		"Tile the given images in reverse order",
+	)
+
+	fs.StringVar(
+		&f.filesFrom,
+		"files-from",
+		"",
+		"Read the images list from the given file ('-' for stdin), one path "+
+			"per line or NUL separated",
+	)
+
+	fs.BoolVar(&f.sorted, "sort", false, "Sort the given images by name")
+
+	fs.BoolVar(
+		&f.recursive,
+		"r",
+		false,
+		"Tile directories recursively, each subdirectory is tiled separately",
+	)
+}
+
+// groups returns the groups of images referenced by paths and by the
+// -files-from list, in the requested order. Images from archives can be read
+// until f is closed.
+func (f *inputFlags) groups(paths []string) ([]*group, error) {
+	if f.filesFrom != "" {
+		list, err := readFileList(f.filesFrom)
+
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, list...)
+	}
+
+	groups, err := findGroups(paths, f.recursive, &f.archives)
+
+	if err != nil {
+		f.close()
+		return nil, err
+	}
+
+	for _, g := range groups {
+		if f.sorted {
+			g.sort()
+		}
+
+		if f.reverse {
+			g.reverse()
+		}
+	}
+
+	return groups, nil
+}
+
+// close releases the input archives opened by groups, their images can't be
+// read after it.
+func (f *inputFlags) close() {
+	if err := f.archives.Close(); err != nil {
+		log.Printf("Can't close the input archives -> %v\n", err)
+	}
+}
+
+// group is a set of images that are tiled together, images from different
+// groups never share an output sheet.
+type group struct {
+	name   string
+	images []source
+}
+
+// sort sorts the images of g by name.
+func (g *group) sort() {
+	sort.SliceStable(g.images, func(i, j int) bool {
+		return g.images[i].name < g.images[j].name
+	})
+}
+
+// reverse reverses the order of the images of g.
+func (g *group) reverse() {
+	for i, j := 0, len(g.images)-1; i < j; i, j = i+1, j-1 {
+		g.images[i], g.images[j] = g.images[j], g.images[i]
+	}
+}
+
+// impose reorders the images of g as pages of a printed layout, so they can
+// be split in sheets (see sheets) of 2 tiles, alternating front and back
+// sides. The only supported mode is "booklet", which places pages for
+// saddle-stitched booklets (e.g. 8,1 / 2,7 / 6,3 / 4,5), padding them with
+// blank pages to a multiple of 4.
+func (g *group) impose(mode string) error {
+	if mode != "booklet" {
+		return fmt.Errorf("unknown imposition '%s'", mode)
+	}
+
+	n := (len(g.images) + 3) / 4 * 4
+	pages := make([]source, n)
+	copy(pages, g.images)
+	images := make([]source, 0, n)
+
+	for i := 0; i < n/2; i += 2 {
+		images = append(
+			images,
+			pages[n-1-i], pages[i],
+			pages[i+1], pages[n-2-i],
+		)
+	}
+
+	g.images = images
+	return nil
+}
+
+// sheets splits the images of g in sheets of n tiles.
+func (g *group) sheets(n int64) []sheet {
+	var ss []sheet
+
+	for i := int64(0); i*n < int64(len(g.images)); i++ {
+		a := i * n
+		b := a + n
+
+		if b > int64(len(g.images)) {
+			b = int64(len(g.images))
+		}
+
+		ss = append(ss, sheet{group: g.name, n: i, images: g.images[a:b], first: a})
+	}
+
+	return ss
+}
+
+// repeat replaces every image of g with the given number of copies of it,
+// so they can be split in sheets (see sheets) for step-and-repeat printing.
+// If copies is less than 1, every image fills the n tiles of its own sheet.
+func (g *group) repeat(n, copies int64) {
+	if copies < 1 {
+		copies = n
+	}
+
+	images := make([]source, 0, int64(len(g.images))*copies)
+
+	for _, img := range g.images {
+		for i := int64(0); i < copies; i++ {
+			images = append(images, img)
+		}
+	}
+
+	g.images = images
+}
+
+// sheet is an output file of a group.
+type sheet struct {
+	group  string
+	n      int64
+	images []source
+
+	// first is the position of the first image of the sheet in its group.
+	first int64
+
+	// back is true for the back side of duplex sheets, whose tiles are
+	// mirrored (see withBacks).
+	back bool
+}
+
+// readBacks returns the back images at p, a single image used for every
+// front, or a list of images (see readFileList). Images are told apart from
+// lists by their content, not by their name.
+func readBacks(p string) ([]source, error) {
+	if p != "-" {
+		src := fileSource(p)
+		f, err := src.open()
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, _, err = image.DecodeConfig(f)
+		closeFile(p, f)
+
+		if err == nil {
+			return []source{src}, nil
+		}
+	}
+
+	paths, err := readFileList(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	backs := make([]source, len(paths))
+
+	for i, p := range paths {
+		backs[i] = fileSource(p)
+	}
+
+	return backs, nil
+}
+
+// withBacks interleaves a back sheet after every front sheet. The back of
+// every front image is the image with the same index in backs, or the only
+// one if backs has a single image, so backs must have a single image or as
+// many as the fronts. Output numbers are changed to keep the interleaved
+// order.
+func withBacks(fronts []sheet, backs []source) ([]sheet, error) {
+	n := 0
+
+	for _, f := range fronts {
+		n += len(f.images)
+	}
+
+	if len(backs) != 1 && len(backs) != n {
+		return nil, fmt.Errorf("%d back images given for %d front images", len(backs), n)
+	}
+
+	var (
+		sheets []sheet
+		k      int
+	)
+
+	for _, f := range fronts {
+		images := make([]source, len(f.images))
+
+		for i := range images {
+			if len(backs) == 1 {
+				images[i] = backs[0]
+				continue
+			}
+
+			images[i] = backs[k]
+			k++
+		}
+
+		back := sheet{group: f.group, n: 2*f.n + 1, images: images, first: f.first, back: true}
+		f.n *= 2
+		sheets = append(sheets, f, back)
+	}
+
+	return sheets, nil
+}
+
+// findGroups returns the groups of images referenced by paths. Files and
+// archive entries are added to the root group ("."). Directories are only
+// allowed when recursive is true, in which case they are walked and every
+// subdirectory becomes its own group, named after its path relative to the
+// given directory. Archives found while walking are treated as
+// subdirectories named after the archive. Archives are read with a, which
+// should be closed once the images aren't needed anymore.
+func findGroups(paths []string, recursive bool, a *archives) ([]*group, error) {
+	var groups []*group
+	index := make(map[string]*group)
+
+	add := func(name string, src source) {
+		g, ok := index[name]
+
+		if !ok {
+			g = &group{name: name}
+			index[name] = g
+			groups = append(groups, g)
+		}
+
+		g.images = append(g.images, src)
+	}
+
+	// addArchive adds the archive entries to the root group, or if dir is
+	// given, to groups named after dir, the archive and the entry directory.
+	addArchive := func(dir, p string) error {
+		return a.read(p, func(entry string, src source) error {
+			name := "."
+
+			if dir != "" {
+				name = path.Join(dir, archiveName(p), path.Dir(entry))
+				name = filepath.FromSlash(name)
+			}
+
+			add(name, src)
+			return nil
+		})
+	}
+
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+
+		// Missing files are reported when opened, along with other errors
+		// related to specific images.
+		if err != nil || !fi.IsDir() && archiveFormat(p) == "" {
+			add(".", fileSource(p))
+			continue
+		}
+
+		if !fi.IsDir() {
+			if err := addArchive("", p); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !recursive {
+			return nil, fmt.Errorf("'%s' is a directory, use -r for tiling directories", p)
+		}
+
+		root := filepath.Clean(p)
+
+		err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if fi.IsDir() {
+				return nil
+			}
+
+			name, err := filepath.Rel(root, filepath.Dir(p))
+
+			if err != nil {
+				return err
+			}
+
+			if archiveFormat(p) != "" {
+				return addArchive(filepath.ToSlash(name), p)
+			}
+
+			if isImage(p) {
+				add(name, fileSource(p))
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+// readFileList returns the paths listed in the file at p, or in the standard
+// input if p is "-". Paths may be separated by new lines or by NUL characters
+// (as produced by 'find -print0'), empty paths are ignored.
+func readFileList(p string) ([]string, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if p == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(filepath.Clean(p))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sep := []byte("\n")
+
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+
+	for _, l := range bytes.Split(data, sep) {
+		l = bytes.TrimSuffix(l, []byte("\r"))
+
+		if len(l) > 0 {
+			paths = append(paths, string(l))
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestGroupImpose(t *testing.T) {
+	cases := []struct {
+		pages int
+
+		// want has the page numbers in sheet order, 0 is a blank page.
+		want []int
+	}{
+		{0, []int{}},
+		{1, []int{0, 1, 0, 0}},
+		{4, []int{4, 1, 2, 3}},
+		{5, []int{0, 1, 2, 0, 0, 3, 4, 5}},
+		{8, []int{8, 1, 2, 7, 6, 3, 4, 5}},
+		{12, []int{12, 1, 2, 11, 10, 3, 4, 9, 8, 5, 6, 7}},
+	}
+
+	for _, c := range cases {
+		g := &group{name: "."}
+
+		for i := 1; i <= c.pages; i++ {
+			g.images = append(g.images, fileSource(strconv.Itoa(i)))
+		}
+
+		if err := g.impose("booklet"); err != nil {
+			t.Errorf("%d pages: unexpected error %v", c.pages, err)
+			continue
+		}
+
+		got := make([]int, len(g.images))
+
+		for i, img := range g.images {
+			if !img.blank() {
+				got[i], _ = strconv.Atoi(img.name)
+			}
+		}
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%d pages: got %v, want %v", c.pages, got, c.want)
+		}
+	}
+
+	g := &group{images: []source{fileSource("1")}}
+
+	if err := g.impose("calendar"); err == nil {
+		t.Error("unknown imposition: expected an error")
+	}
+}
+
+func TestWithBacks(t *testing.T) {
+	fronts := []sheet{
+		{group: ".", n: 0, images: []source{fileSource("f1"), fileSource("f2")}},
+		{group: ".", n: 1, images: []source{fileSource("f3")}, first: 2},
+	}
+
+	cases := []struct {
+		backs []string
+
+		// want has the image names and output numbers of the sheets, nil
+		// means an error.
+		want  [][]string
+		wantN []int64
+	}{
+		{
+			[]string{"b"},
+			[][]string{{"f1", "f2"}, {"b", "b"}, {"f3"}, {"b"}},
+			[]int64{0, 1, 2, 3},
+		},
+		{
+			[]string{"b1", "b2", "b3"},
+			[][]string{{"f1", "f2"}, {"b1", "b2"}, {"f3"}, {"b3"}},
+			[]int64{0, 1, 2, 3},
+		},
+		{nil, nil, nil},
+		{[]string{"b1", "b2"}, nil, nil},
+		{[]string{"b1", "b2", "b3", "b4"}, nil, nil},
+	}
+
+	for _, c := range cases {
+		backs := make([]source, len(c.backs))
+
+		for i, b := range c.backs {
+			backs[i] = fileSource(b)
+		}
+
+		sheets, err := withBacks(fronts, backs)
+
+		if (err != nil) != (c.want == nil) {
+			t.Errorf("%d backs: got error %v, want error %v", len(c.backs), err, c.want == nil)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		var got [][]string
+		var gotN []int64
+
+		for _, s := range sheets {
+			names := make([]string, len(s.images))
+
+			for i, img := range s.images {
+				names[i] = img.name
+			}
+
+			got = append(got, names)
+			gotN = append(gotN, s.n)
+		}
+
+		if !reflect.DeepEqual(got, c.want) || !reflect.DeepEqual(gotN, c.wantN) {
+			t.Errorf("%d backs: got %v %v, want %v %v", len(c.backs), got, gotN, c.want, c.wantN)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// labelTemplate is a sheet of pre-cut labels or cards. Lengths have a
+// physical unit (see parseLength and parseSize).
+type labelTemplate struct {
+	// Page is the paper size.
+	Page string
+
+	// Cols and Rows are the number of labels per row and column.
+	Cols, Rows int
+
+	// Label is the size of every label.
+	Label string
+
+	// Offset is the distance from the top left corner of the page to the top
+	// left corner of the first label.
+	Offset string
+
+	// Pitch is the distance between the top left corners of neighbor labels.
+	Pitch string
+
+	// Radius is the corner radius of the labels.
+	Radius string
+}
+
+// LabelTemplates is a set of standard label and card stock sheets.
+var LabelTemplates = map[string]labelTemplate{
+	// Business cards, 10 per sheet.
+	"avery5371": {"8.5x11in", 2, 5, "3.5x2in", "0.75x0.5in", "3.5x2in", "0"},
+	"avery8371": {"8.5x11in", 2, 5, "3.5x2in", "0.75x0.5in", "3.5x2in", "0"},
+
+	// Address labels, 30 per sheet.
+	"avery5160": {"8.5x11in", 3, 10, "2.625x1in", "0.1875x0.5in", "2.75x1in", "0.0625in"},
+	"avery8160": {"8.5x11in", 3, 10, "2.625x1in", "0.1875x0.5in", "2.75x1in", "0.0625in"},
+
+	// Address labels, 20 per sheet.
+	"avery5161": {"8.5x11in", 2, 10, "4x1in", "0.15625x0.5in", "4.1875x1in", "0.0625in"},
+
+	// Shipping labels, 10 per sheet.
+	"avery5163": {"8.5x11in", 2, 5, "4x2in", "0.15625x0.5in", "4.1875x2in", "0.0625in"},
+
+	// Shipping labels, 6 per sheet.
+	"avery5164": {"8.5x11in", 2, 3, "4x3.333in", "0.15625x0.5in", "4.1875x3.333in", "0.0625in"},
+
+	// Return address labels, 80 per sheet.
+	"avery5167": {"8.5x11in", 4, 20, "1.75x0.5in", "0.28125x0.5in", "2.0625x0.5in", "0.0625in"},
+
+	// A4 address labels, 21 per sheet.
+	"l7160": {"210x297mm", 3, 7, "63.5x38.1mm", "7.21x15.15mm", "66.04x38.1mm", "2mm"},
+
+	// A4 address labels, 14 per sheet.
+	"l7163": {"210x297mm", 2, 7, "99.1x38.1mm", "4.65x15.15mm", "101.6x38.1mm", "2mm"},
+
+	// A4 business cards, 10 per sheet.
+	"c32011": {"210x297mm", 2, 5, "85x54mm", "15x13.5mm", "95x54mm", "0"},
+}
+
+// slots returns the page and the label areas of t at dpi, shifted by off.
+// Labels are ordered by row and their shapes have rounded corners.
+func (t labelTemplate) slots(dpi int, off image.Point) (image.Rectangle, []image.Rectangle, []tile.Polygon, error) {
+	page, err := parseSize(t.Page, dpi)
+
+	if err != nil {
+		return image.Rectangle{}, nil, nil, err
+	}
+
+	label, err := parseSize(t.Label, dpi)
+
+	if err != nil {
+		return image.Rectangle{}, nil, nil, err
+	}
+
+	first, err := parseSize(t.Offset, dpi)
+
+	if err != nil {
+		return image.Rectangle{}, nil, nil, err
+	}
+
+	first = first.Add(off)
+
+	// Pitches aren't rounded, so rounding errors don't pile up along the
+	// page.
+	px, py, err := parseExactSize(t.Pitch, dpi)
+
+	if err != nil {
+		return image.Rectangle{}, nil, nil, err
+	}
+
+	radius, err := parseLength(t.Radius, dpi)
+
+	if err != nil {
+		return image.Rectangle{}, nil, nil, err
+	}
+
+	var (
+		slots  []image.Rectangle
+		shapes []tile.Polygon
+	)
+
+	for l := 0; l < t.Rows; l++ {
+		for c := 0; c < t.Cols; c++ {
+			p := first.Add(image.Pt(
+				int(math.Round(float64(c)*px)),
+				int(math.Round(float64(l)*py)),
+			))
+
+			r := image.Rectangle{p, p.Add(label)}
+			slots = append(slots, r)
+			shapes = append(shapes, tile.RoundedRect(r, float64(radius)))
+		}
+	}
+
+	return image.Rect(0, 0, page.X, page.Y), slots, shapes, nil
+}
+
+// parseCalibration returns the offset s ("X,Y", with optional units, like
+// "1mm,-0.5mm") in pixels at dpi.
+func parseCalibration(s string, dpi int) (image.Point, error) {
+	parts := strings.Split(s, ",")
+
+	if len(parts) != 2 {
+		return image.Point{}, fmt.Errorf("invalid offset '%s'", s)
+	}
+
+	x, err := parseLength(parts[0], dpi)
+
+	if err != nil {
+		return image.Point{}, err
+	}
+
+	y, err := parseLength(parts[1], dpi)
+
+	if err != nil {
+		return image.Point{}, err
+	}
+
+	return image.Pt(x, y), nil
+}
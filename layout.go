@@ -0,0 +1,272 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// sheetLayout describes the placement of the images of an output sheet.
+type sheetLayout struct {
+	Output string       `json:"output"`
+	Group  string       `json:"group"`
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+	DPI    int          `json:"dpi,omitempty"`
+	Slots  []slotLayout `json:"slots"`
+}
+
+// slotLayout describes the placement of an image in a tile.
+type slotLayout struct {
+	Slot   int64   `json:"slot"`
+	Image  string  `json:"image"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Cell   rect    `json:"cell"`
+	Tile   rect    `json:"tile"`
+	Crop   rect    `json:"crop"`
+	Dest   rect    `json:"dest"`
+	Scale  float64 `json:"scale"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// rect is a JSON friendly image.Rectangle.
+type rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+func newRect(r image.Rectangle) rect {
+	return rect{X: r.Min.X, Y: r.Min.Y, W: r.Dx(), H: r.Dy()}
+}
+
+// Rectangle returns r as an image.Rectangle.
+func (r rect) Rectangle() image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+}
+
+func (r rect) String() string {
+	return fmt.Sprintf("%dx%d+%d+%d", r.W, r.H, r.X, r.Y)
+}
+
+// newSheetLayout returns the layout of s without slots, written to output
+// with the given size (see OutputSizes).
+func newSheetLayout(s sheet, output, size string) sheetLayout {
+	r := OutputSizes[size]
+
+	return sheetLayout{
+		Output: output,
+		Group:  s.group,
+		Width:  r.Dx(),
+		Height: r.Dy(),
+		DPI:    OutputDPI[size],
+		Slots:  make([]slotLayout, len(s.images)),
+	}
+}
+
+// newSlotLayout returns the layout of the img image with src bounds placed at
+// the off tile, which fills the cell area of the grid.
+func newSlotLayout(off int64, img string, src, cell image.Rectangle, p tile.Placement) slotLayout {
+	return slotLayout{
+		Slot:   off,
+		Image:  img,
+		Width:  src.Dx(),
+		Height: src.Dy(),
+		Cell:   newRect(cell),
+		Tile:   newRect(p.Tile),
+		Crop:   newRect(p.Src),
+		Dest:   newRect(p.Dst),
+		Scale:  p.Scale,
+	}
+}
+
+// planSheet returns the layout of s in a sheet with the given size and tiles
+// (see sheetTiles), only image headers are decoded.
+func planSheet(s sheet, output, size string, grid []image.Rectangle, f *tile.Format) sheetLayout {
+	l := newSheetLayout(s, output, size)
+
+	for i, img := range s.images {
+		if img.blank() {
+			l.Slots[i] = slotLayout{Slot: int64(i), Cell: newRect(grid[i])}
+			continue
+		}
+
+		bounds, err := img.bounds()
+
+		if err != nil {
+			l.Slots[i] = slotLayout{
+				Slot:  int64(i),
+				Image: img.name,
+				Cell:  newRect(grid[i]),
+				Tile:  newRect(grid[i]),
+				Error: err.Error(),
+			}
+
+			continue
+		}
+
+		p := f.Place(grid[i], bounds)
+		l.Slots[i] = newSlotLayout(int64(i), img.name, bounds, grid[i], p)
+	}
+
+	return l
+}
+
+// TileShapes is the set of supported tile shapes.
+var TileShapes = map[string]bool{
+	"rect":     true,
+	"hex":      true,
+	"triangle": true,
+}
+
+// sheetTiles returns the tiles of a sheet with the given size and tile shape,
+// along with their shapes. Rectangular tiles have no shapes.
+func sheetTiles(shape, size string, tiles int64) ([]image.Rectangle, []tile.Polygon) {
+	r := OutputSizes[size]
+
+	switch shape {
+	case "hex":
+		return tile.HexGrid(r, tiles)
+	case "triangle":
+		return tile.TriangleGrid(r, tiles)
+	}
+
+	return tile.Grid(r, tiles), nil
+}
+
+// mirrorTiles returns the tiles and shapes (see sheetTiles) of the back of a
+// page, so they align with the front after flipping it. A "long" flip mirrors
+// tiles horizontally and a "short" one vertically.
+func mirrorTiles(page image.Rectangle, grid []image.Rectangle, shapes []tile.Polygon, flip string) ([]image.Rectangle, []tile.Polygon) {
+	mx, my := page.Min.X+page.Max.X, page.Min.Y+page.Max.Y
+	mgrid := make([]image.Rectangle, len(grid))
+
+	for i, r := range grid {
+		if flip == "short" {
+			mgrid[i] = image.Rect(r.Min.X, my-r.Max.Y, r.Max.X, my-r.Min.Y)
+		} else {
+			mgrid[i] = image.Rect(mx-r.Max.X, r.Min.Y, mx-r.Min.X, r.Max.Y)
+		}
+	}
+
+	if shapes == nil {
+		return mgrid, nil
+	}
+
+	mshapes := make([]tile.Polygon, len(shapes))
+
+	for i, p := range shapes {
+		mp := make(tile.Polygon, len(p))
+
+		for j, v := range p {
+			if flip == "short" {
+				v[1] = float64(my) - v[1]
+			} else {
+				v[0] = float64(mx) - v[0]
+			}
+
+			mp[j] = v
+		}
+
+		mshapes[i] = mp
+	}
+
+	return mgrid, mshapes
+}
+
+// newSheet returns a Tiler with the given background, size, tiles and tile
+// shapes (see sheetTiles).
+func newSheet(bg color.Color, size string, grid []image.Rectangle, shapes []tile.Polygon) *tile.Tiler {
+	if shapes == nil {
+		return tile.NewWithTiles(bg, OutputSizes[size], grid)
+	}
+
+	masks := make([]image.Image, len(shapes))
+
+	for i, p := range shapes {
+		masks[i] = p
+	}
+
+	return tile.NewWithMasks(bg, OutputSizes[size], grid, masks)
+}
+
+// writeManifest writes the JSON encoded layouts to the output file with the
+// given name.
+func writeManifest(out sink, name string, v interface{}) error {
+	w, err := out.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(v)
+
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// manifestName returns the name of the sidecar manifest of the given output
+// file.
+func manifestName(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".json"
+}
+
+// writePlan writes the given layouts to w in the f format ("text" or
+// "json").
+func writePlan(w io.Writer, layouts []sheetLayout, f string) error {
+	if f == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(layouts)
+	}
+
+	if f != "text" {
+		return fmt.Errorf("unknown plan format '%s'", f)
+	}
+
+	for _, l := range layouts {
+		fmt.Fprintf(w, "%s (%dx%d, group '%s')\n", l.Output, l.Width, l.Height, l.Group)
+
+		for _, s := range l.Slots {
+			if s.Image == "" {
+				fmt.Fprintf(w, "  #%d (blank)\n", s.Slot)
+				continue
+			}
+
+			if s.Error != "" {
+				fmt.Fprintf(w, "  #%d %s: %s\n", s.Slot, s.Image, s.Error)
+				continue
+			}
+
+			fmt.Fprintf(
+				w,
+				"  #%d %s (%dx%d): crop %v -> %v, scale %.3f\n",
+				s.Slot,
+				s.Image,
+				s.Width,
+				s.Height,
+				s.Crop,
+				s.Dest,
+				s.Scale,
+			)
+		}
+	}
+
+	return nil
+}
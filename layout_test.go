@@ -0,0 +1,69 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+func TestMirrorTiles(t *testing.T) {
+	page := image.Rect(0, 0, 100, 200)
+	grid := []image.Rectangle{image.Rect(0, 0, 40, 50), image.Rect(60, 100, 100, 200)}
+	shapes := []tile.Polygon{{{10, 20}, {30, 40}, {0, 200}}, {{60, 100}, {100, 100}, {100, 200}}}
+
+	cases := []struct {
+		flip       string
+		shapes     []tile.Polygon
+		wantGrid   []image.Rectangle
+		wantShapes []tile.Polygon
+	}{
+		{
+			"long",
+			nil,
+			[]image.Rectangle{image.Rect(60, 0, 100, 50), image.Rect(0, 100, 40, 200)},
+			nil,
+		},
+		{
+			"short",
+			nil,
+			[]image.Rectangle{image.Rect(0, 150, 40, 200), image.Rect(60, 0, 100, 100)},
+			nil,
+		},
+		{
+			"long",
+			shapes,
+			[]image.Rectangle{image.Rect(60, 0, 100, 50), image.Rect(0, 100, 40, 200)},
+			[]tile.Polygon{{{90, 20}, {70, 40}, {100, 200}}, {{40, 100}, {0, 100}, {0, 200}}},
+		},
+		{
+			"short",
+			shapes,
+			[]image.Rectangle{image.Rect(0, 150, 40, 200), image.Rect(60, 0, 100, 100)},
+			[]tile.Polygon{{{10, 180}, {30, 160}, {0, 0}}, {{60, 100}, {100, 100}, {100, 0}}},
+		},
+	}
+
+	for _, c := range cases {
+		g, s := mirrorTiles(page, grid, c.shapes, c.flip)
+
+		if !reflect.DeepEqual(g, c.wantGrid) {
+			t.Errorf("%s flip: got tiles %v, want %v", c.flip, g, c.wantGrid)
+		}
+
+		if !reflect.DeepEqual(s, c.wantShapes) {
+			t.Errorf("%s flip: got shapes %v, want %v", c.flip, s, c.wantShapes)
+		}
+	}
+
+	// The front tiles are left untouched.
+	if grid[0] != image.Rect(0, 0, 40, 50) || shapes[0][0] != (f64.Vec2{10, 20}) {
+		t.Error("the front tiles were modified")
+	}
+}
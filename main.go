@@ -7,12 +7,14 @@ import (
 	"flag"
 	"fmt"
 	"image"
-	"image/jpeg"
+	"image/color"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/image/colornames"
@@ -20,6 +22,7 @@ import (
 	"github.com/ntrrg/tiler/pkg/tile"
 
 	_ "golang.org/x/image/webp"
+	_ "image/gif"
 	_ "image/png"
 )
 
@@ -32,24 +35,125 @@ var OutputSizes = map[string]image.Rectangle{
 	"hletter72":  image.Rect(0, 0, 792, 612),
 	"hletter200": image.Rect(0, 0, 2200, 1700),
 	"hletter300": image.Rect(0, 0, 3300, 2550),
+
+	"a4_72":  image.Rect(0, 0, 595, 842),
+	"a4_200": image.Rect(0, 0, 1654, 2339),
+	"a4_300": image.Rect(0, 0, 2480, 3508),
+
+	"ha4_72":  image.Rect(0, 0, 842, 595),
+	"ha4_200": image.Rect(0, 0, 2339, 1654),
+	"ha4_300": image.Rect(0, 0, 3508, 2480),
+
+	"4x6_72":  image.Rect(0, 0, 288, 432),
+	"4x6_200": image.Rect(0, 0, 800, 1200),
+	"4x6_300": image.Rect(0, 0, 1200, 1800),
+
+	"h4x6_72":  image.Rect(0, 0, 432, 288),
+	"h4x6_200": image.Rect(0, 0, 1200, 800),
+	"h4x6_300": image.Rect(0, 0, 1800, 1200),
+}
+
+// OutputDPI is the resolution of the output sizes, in dots per inch.
+var OutputDPI = map[string]int{
+	"letter72":  72,
+	"letter200": 200,
+	"letter300": 300,
+
+	"hletter72":  72,
+	"hletter200": 200,
+	"hletter300": 300,
+
+	"a4_72":  72,
+	"a4_200": 200,
+	"a4_300": 300,
+
+	"ha4_72":  72,
+	"ha4_200": 200,
+	"ha4_300": 300,
+
+	"4x6_72":  72,
+	"4x6_200": 200,
+	"4x6_300": 300,
+
+	"h4x6_72":  72,
+	"h4x6_200": 200,
+	"h4x6_300": 300,
+}
+
+// Placeholder is the color used for filling tiles whose image couldn't be
+// drawn.
+var Placeholder color.Color = colornames.Lightgray
+
+// info is where informative messages are written.
+var info io.Writer = os.Stdout
+
+// command is a tiler subcommand.
+type command struct {
+	run  func(args []string)
+	help string
+}
+
+// commands are the available subcommands, when none is given, the images are
+// tiled.
+var commands = map[string]command{
+	"atlas":   {runAtlas, "Pack the given images into texture atlases"},
+	"animate": {runAnimate, "Build animated GIFs from the tiles of the given sheets"},
+	"check":   {runCheck, "Validate the given images without composing them"},
+	"explode": {runExplode, "Compose the frames of the given GIFs into sheets"},
+	"extract": {runExtract, "Extract the photos of the given flatbed scans"},
+	"idphoto": {runIDPhoto, "Fill sheets with ID photos of exact physical sizes"},
+	"mosaic":  {runMosaic, "Build a photomosaic of an image from the given images"},
+	"pattern": {runPattern, "Repeat the given images to fill a sheet as a pattern"},
+	"poster":  {runPoster, "Enlarge an image across multiple pages"},
+	"pyramid": {runPyramid, "Cut an image into a zoomable tile pyramid"},
+	"split":   {runSplit, "Extract the tiles of the given sheets"},
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+
 	start := time.Now()
 
 	var (
-		verbose bool
-		debug   bool
-		dryrun  bool
-		tiles   int64
-		bg      string
-		reverse bool
-		size    string
-		output  string
+		verbose   bool
+		debug     bool
+		dryrun    bool
+		tiles     int64
+		bg        string
+		size      string
+		output    string
+		archive   string
+		jobs      int
+		keepGoing bool
+		skipBad   bool
+		plan      string
+		manifest  string
+		shape     string
+		repeat    bool
+		template  string
+		backs     string
+		impose    string
+		flip      string
+		marks     markOptions
+		captions  captionOptions
+		markColor string
+		calibrate string
+		copies    int64
+		outline   int
+		lineColor string
+		in        inputFlags
 
 		format = tile.DefaultFormat
 	)
 
+	in.register(flag.CommandLine)
+	captions.register(flag.CommandLine)
+
 	flag.BoolVar(&verbose, "v", false, "Verbose output")
 	flag.BoolVar(&debug, "debug", false, "Enable debugging")
 
@@ -60,16 +164,132 @@ func main() {
 		"Process the images but don't write to disk",
 	)
 
+	flag.StringVar(
+		&plan,
+		"plan",
+		"",
+		"Print the images layout ('text' or 'json') without decoding or "+
+			"writing anything",
+	)
+
+	flag.StringVar(
+		&manifest,
+		"manifest",
+		"",
+		"Write a JSON manifest with the images layout, 'sheet' writes one next "+
+			"to every output file, any other value is used as the name of a "+
+			"manifest for all the output files",
+	)
+
 	flag.BoolVar(
-		&reverse,
-		"reverse",
+		&keepGoing,
+		"keep-going",
 		false,
-		"Tile the given images in reverse order",
+		"Don't stop at unreadable images, leave a placeholder instead",
+	)
+
+	flag.BoolVar(
+		&skipBad,
+		"skip-bad",
+		false,
+		"With -keep-going, skip unreadable images and reflow the remaining ones",
 	)
 
 	flag.Int64Var(&tiles, "tiles", 4, "[WIP] Number of tiles, at least 2")
+
+	flag.BoolVar(
+		&repeat,
+		"repeat",
+		false,
+		"Step-and-repeat, every image fills all the tiles of its own sheet "+
+			"(see -copies), images are decoded only once per sheet",
+	)
+
+	flag.Int64Var(
+		&copies,
+		"copies",
+		0,
+		"With -repeat, number of copies of every image instead of a full sheet",
+	)
+	flag.IntVar(
+		&jobs,
+		"jobs",
+		runtime.GOMAXPROCS(0),
+		"Maximum number of output files processed concurrently",
+	)
+
 	flag.StringVar(&size, "size", "letter300", "Output file size")
 	flag.StringVar(&bg, "bg", "white", "Output file background color")
+	flag.StringVar(
+		&shape,
+		"shape",
+		"rect",
+		"Tile shape, 'rect', 'hex' or 'triangle', hexagonal and triangular "+
+			"tiles use the 'cover' resizing mode unless -resize is given",
+	)
+
+	flag.StringVar(
+		&template,
+		"template",
+		"",
+		"Label or card stock template (e.g. avery5160, l7160), its labels are "+
+			"used as tiles and -size only sets the resolution",
+	)
+
+	flag.StringVar(
+		&calibrate,
+		"calibrate",
+		"0,0",
+		"With -template, offset of the labels as X,Y (px, in, cm, mm or pt) "+
+			"to compensate printer drift",
+	)
+
+	flag.IntVar(&outline, "outline", 0, "Width of the tile outlines, in pixels")
+	flag.StringVar(&lineColor, "outline-color", "black", "Tile outlines color")
+	flag.StringVar(
+		&impose,
+		"impose",
+		"",
+		"Reorder the images for a printed layout, 'booklet' places them two "+
+			"per sheet for saddle-stitched booklets, alternating front and back "+
+			"sides, portrait sizes are replaced by their landscape version",
+	)
+
+	flag.StringVar(
+		&backs,
+		"backs",
+		"",
+		"Back images for duplex printing, a single image for every front or "+
+			"a list of images (see -files-from), back sheets are interleaved "+
+			"after their fronts",
+	)
+
+	flag.StringVar(
+		&flip,
+		"flip",
+		"long",
+		"With -backs, how the sheets are flipped, 'long' or 'short' edge",
+	)
+
+	flag.BoolVar(&marks.crop, "crop-marks", false, "Draw crop marks at the tile corners")
+	flag.BoolVar(&marks.cut, "cut-lines", false, "Draw dashed cut lines around the tiles")
+
+	flag.BoolVar(
+		&marks.registration,
+		"reg-marks",
+		false,
+		"Draw registration marks in the page margins",
+	)
+
+	flag.IntVar(
+		&marks.width,
+		"mark-width",
+		0,
+		"Width of the marks and cut lines, in pixels, computed from the "+
+			"resolution if 0",
+	)
+
+	flag.StringVar(&markColor, "mark-color", "black", "Color of the marks and cut lines")
 	flag.StringVar(&format.Resize, "resize", "contain", "Resizing mode")
 	flag.Int64Var(&format.Margin, "margin", 0, "Margin")
 	flag.StringVar(&format.Align, "align", "center", "Horizontal alignment")
@@ -79,129 +299,485 @@ func main() {
 		&output,
 		"o",
 		"output%d.jpg",
-		"Output file, %d in file name is replaced by file number",
+		"Output file, %d in file name is replaced by file number and {group} "+
+			"by the subdirectory name (see -r)",
 	)
 
+	flag.StringVar(
+		&archive,
+		"archive",
+		"",
+		"Write output files into the given archive (.zip, .cbz, .tar, .tar.gz) "+
+			"using -o as entry name, '-' writes a tar stream to stdout",
+	)
+
+	flag.Usage = usage
 	flag.Parse()
 
 	if debug {
 		verbose = true
 	}
 
+	if jobs < 1 {
+		jobs = 1
+	}
+
 	log.SetFlags(0)
-	images := flag.Args()
+	marks.color = colornames.Map[markColor]
 
-	ni := len(images)
+	if impose != "" {
+		tiles = 2
 
-	if ni < 2 {
-		log.Fatalln("At least 1 image should be given")
+		// Booklet pages go side by side, so sheets are landscape.
+		if r := OutputSizes[size]; template == "" && r.Dy() > r.Dx() {
+			if _, ok := OutputSizes["h"+size]; !ok {
+				log.Fatalf("The size '%s' has no landscape version for -impose\n", size)
+			}
+
+			size = "h" + size
+		}
+	}
+
+	if !TileShapes[shape] {
+		log.Fatalf("Unknown tile shape '%s'\n", shape)
 	}
 
-	if reverse {
-		for i, j := 0, ni-1; i < ni/2; i, j = i+1, j-1 {
-			images[i], images[j] = images[j], images[i]
+	if shape != "rect" && !isFlagSet(flag.CommandLine, "resize") {
+		format.Resize = "cover"
+	}
+
+	var (
+		labels      []image.Rectangle
+		labelShapes []tile.Polygon
+	)
+
+	if template != "" {
+		t, ok := LabelTemplates[template]
+
+		if !ok {
+			log.Fatalf("Unknown template '%s'\n", template)
 		}
+
+		dpi := OutputDPI[size]
+		off, err := parseCalibration(calibrate, dpi)
+
+		if err != nil {
+			log.Fatalf("Invalid calibration -> %v\n", err)
+		}
+
+		page, slots, shapes, err := t.slots(dpi, off)
+
+		if err != nil {
+			log.Fatalf("Invalid template '%s' -> %v\n", template, err)
+		}
+
+		// The template is used as the output size from now on.
+		OutputSizes[template] = page
+		OutputDPI[template] = dpi
+		size = template
+		tiles = int64(len(slots))
+		labels, labelShapes = slots, shapes
 	}
 
-	var wt sync.WaitGroup
-	nt := int64(ni) / tiles
-	extra := int64(ni) % tiles
+	if captions.enabled() {
+		if shape != "rect" {
+			log.Fatalln("Captions are only supported with rectangular tiles")
+		}
+
+		if err := captions.load(OutputDPI[size]); err != nil {
+			log.Fatalf("Can't load the captions -> %v\n", err)
+		}
+
+		format.Caption = captions.reserve()
+	}
+
+	// All the sheets share the same tiles.
+	grid, shapes := sheetTiles(shape, size, tiles)
+
+	if template != "" {
+		grid, shapes = labels, labelShapes
+	} else if impose != "" {
+		// Booklet pages go side by side.
+		grid, shapes = tile.Cells(OutputSizes[size], 2, 1), nil
+	}
+
+	backGrid, backShapes := mirrorTiles(OutputSizes[size], grid, shapes, flip)
+
+	// Marks go around the trim boxes, so margins leave room for them.
+	trims, backTrims := trimBoxes(grid, format), trimBoxes(backGrid, format)
+
+	if err := marks.check(OutputSizes[size], trims, OutputDPI[size]); err != nil {
+		log.Fatalf("Can't draw the marks -> %v\n", err)
+	}
 
-	if extra != 0 {
-		nt++
+	if archive == "-" {
+		info = os.Stderr
 	}
 
-	for i := int64(0); i < nt; i++ {
-		a := i * tiles
-		b := a + tiles
+	groups, err := in.groups(flag.Args())
+
+	if err != nil {
+		log.Fatalf("Can't read the given images -> %v\n", err)
+	}
+
+	var (
+		sheets []sheet
+		fails  failures
+	)
+
+	ni := 0
+
+	for _, g := range groups {
+		if keepGoing && skipBad {
+			images := g.images[:0]
+			errs := checkImages(g.images, jobs)
+
+			for i, img := range g.images {
+				if err := errs[i]; err != nil {
+					fails.add("", img.name, err)
+					continue
+				}
 
-		if i == nt-1 && extra != 0 {
-			b += extra - tiles
+				images = append(images, img)
+			}
+
+			g.images = images
 		}
 
-		wt.Add(1)
+		ni += len(g.images)
 
-		go func(nt int64, images []string) {
-			if debug {
-				fmt.Printf("Generating tiled image #%d using %v..\n", nt, images)
+		if repeat {
+			g.repeat(int64(len(grid)), copies)
+		}
+
+		if impose != "" {
+			if err := g.impose(impose); err != nil {
+				log.Fatalf("Can't impose the images -> %v\n", err)
 			}
+		}
 
-			dst := tile.New(colornames.Map[bg], OutputSizes[size], tiles)
+		// Repeated images fill every tile, even the ones of rounded up grids.
+		if repeat {
+			sheets = append(sheets, g.sheets(int64(len(grid)))...)
+		} else {
+			sheets = append(sheets, g.sheets(tiles)...)
+		}
+	}
 
-			for _, imgPath := range images {
-				imgFile, err := os.Open(filepath.Clean(imgPath))
+	if ni < 1 && fails.len() == 0 {
+		log.Fatalln("At least 1 image should be given")
+	}
 
-				if err != nil {
-					log.Fatalf("Can't open image '%v' -> %v\n", imgPath, err)
+	if backs != "" {
+		if flip != "long" && flip != "short" {
+			log.Fatalf("Unknown flip '%s'\n", flip)
+		}
+
+		images, err := readBacks(backs)
+
+		if err != nil {
+			log.Fatalf("Can't read the back images -> %v\n", err)
+		}
+
+		if sheets, err = withBacks(sheets, images); err != nil {
+			log.Fatalf("Can't pair the back images -> %v\n", err)
+		}
+	}
+
+	nt := int64(len(sheets))
+
+	// sheetGrid returns the tiles and shapes of s.
+	sheetGrid := func(s sheet) ([]image.Rectangle, []tile.Polygon) {
+		if s.back {
+			return backGrid, backShapes
+		}
+
+		return grid, shapes
+	}
+
+	if plan != "" {
+		layouts := make([]sheetLayout, len(sheets))
+
+		for i, s := range sheets {
+			name := outputName(output, s.group, s.n)
+			g, _ := sheetGrid(s)
+			layouts[i] = planSheet(s, name, size, g, format)
+		}
+
+		err = writePlan(os.Stdout, layouts, plan)
+		in.close()
+
+		if err != nil {
+			log.Fatalf("Can't write the plan -> %v\n", err)
+		}
+
+		return
+	}
+
+	// Dry runs don't write anything, so the output archive isn't created.
+	var out sink = dirSink{}
+
+	if !dryrun {
+		if out, err = newSink(archive); err != nil {
+			log.Fatalf("Can't create the output archive -> %v\n", err)
+		}
+	}
+
+	// compose draws and writes the s sheet, returning its layout, or nil if
+	// it couldn't be written.
+	compose := func(s sheet) *sheetLayout {
+		name := outputName(output, s.group, s.n)
+		layout := newSheetLayout(s, name, size)
+		grid, shapes := sheetGrid(s)
+
+		if debug {
+			fmt.Fprintf(info, "Generating tiled image #%d of '%s' using %v..\n", s.n, s.group, s.images)
+		}
+
+		dst := newSheet(colornames.Map[bg], size, grid, shapes)
+
+		// Tiles don't overlap, so they are decoded, scaled and drawn
+		// concurrently.
+		var (
+			wt     sync.WaitGroup
+			failed int32
+		)
+
+		// Image dates are read while decoding, for captioning them.
+		var dates []time.Time
+
+		if captions.mode == "date" {
+			dates = make([]time.Time, len(s.images))
+		}
+
+		// Repeated images (see -repeat) are decoded and scaled only once.
+		var unique []source
+		offs := make(map[string][]int64)
+
+		for i, img := range s.images {
+			if _, ok := offs[img.name]; !ok {
+				unique = append(unique, img)
+			}
+
+			offs[img.name] = append(offs[img.name], int64(i))
+		}
+
+		for _, img := range unique {
+			if img.blank() {
+				for _, off := range offs[img.name] {
+					layout.Slots[off] = slotLayout{Slot: off, Cell: newRect(dst.Tile(off))}
 				}
 
+				continue
+			}
+
+			wt.Add(1)
+
+			go func(img source, offs []int64) {
+				defer wt.Done()
+
+				imgPath := img.name
+
 				if debug {
-					fmt.Printf("Writing image '%s at tiled image #%d'..\n", imgPath, nt)
+					fmt.Fprintf(info, "Writing image '%s at tiled image #%d'..\n", imgPath, s.n)
+				}
+
+				var (
+					src  image.Image
+					date time.Time
+					err  error
+				)
+
+				if dates != nil {
+					src, date, err = img.decodeDated()
+				} else {
+					src, err = img.decode()
+				}
+
+				if err != nil {
+					fails.add(name, imgPath, err)
+					atomic.StoreInt32(&failed, 1)
+
+					for _, off := range offs {
+						dst.FillAt(off, Placeholder, format)
+
+						layout.Slots[off] = slotLayout{
+							Slot:  off,
+							Image: imgPath,
+							Cell:  newRect(dst.Tile(off)),
+							Tile:  newRect(dst.Tile(off)),
+							Error: err.Error(),
+						}
+					}
+
+					return
 				}
 
-				_, err = dst.Draw(imgFile, format)
+				scaled, f := src, format
 
-				if err != nil && err != io.EOF {
-					closeFile(imgPath, imgFile)
-					log.Fatalf("Can't decode the image '%v' -> %v\n", imgPath, err)
+				// The image is scaled once for all its tiles and then only
+				// placed in them.
+				if len(offs) > 1 {
+					_, scaled = format.Format(dst.Tile(offs[0]), src)
+					pf := *format
+					pf.Resize = "none"
+					f = &pf
 				}
 
-				closeFile(imgPath, imgFile)
+				for _, off := range offs {
+					dst.DrawImageAt(scaled, off, f)
+					cell := dst.Tile(off)
+					p := format.Place(cell, src.Bounds())
+					layout.Slots[off] = newSlotLayout(off, imgPath, src.Bounds(), cell, p)
+
+					if dates != nil {
+						dates[off] = date
+					}
+				}
 
 				if debug {
-					fmt.Printf("Image '%s' written at tiled image #%d\n", imgPath, nt)
+					fmt.Fprintf(info, "Image '%s' written at tiled image #%d\n", imgPath, s.n)
 				}
+			}(img, offs[img.name])
+		}
+
+		wt.Wait()
+
+		if atomic.LoadInt32(&failed) != 0 && !keepGoing {
+			return nil
+		}
+
+		if captions.enabled() {
+			drawCaptions(dst, s, layout, dates, format, colornames.Map[bg], &captions)
+		}
+
+		for i := range s.images {
+			p := tile.RectPolygon(grid[i])
+
+			if shapes != nil {
+				p = shapes[i]
 			}
 
-			name := filepath.Clean(fmt.Sprintf(output, nt))
+			tile.DrawOutline(dst, p, outline, colornames.Map[lineColor])
+		}
+
+		// Marks are drawn after all the images, so they sit on top.
+		if marks.enabled() {
+			t := trims
 
-			if debug {
-				fmt.Printf("Tiled image #%d generated, writing to '%s'\n", nt, name)
+			if s.back {
+				t = backTrims
 			}
 
-			if !dryrun {
-				imgFile, err := os.Create(name)
+			drawMarks(dst, OutputSizes[size], t[:len(s.images)], OutputDPI[size], marks)
+		}
 
-				if err != nil {
-					log.Fatalf("Can't create the output file -> %v\n", err)
-				}
+		if debug {
+			fmt.Fprintf(info, "Tiled image #%d generated, writing to '%s'\n", s.n, name)
+		}
 
-				defer closeFile(name, imgFile)
+		if !dryrun {
+			if err := writeSheet(out, name, dst); err != nil {
+				fails.add(name, "", err)
+				return nil
+			}
 
-				err = jpeg.Encode(imgFile, dst, nil)
+			if manifest == "sheet" {
+				mname := manifestName(name)
 
-				if err != nil {
-					log.Fatalf("Can't encode the output file -> %v\n", err)
+				if err := writeManifest(out, mname, layout); err != nil {
+					fails.add(mname, "", err)
 				}
 			}
+		}
+
+		if debug {
+			fmt.Fprintf(info, "Tiled image #%d has been written ('%s')\n", s.n, name)
+		}
+
+		return &layout
+	}
+
+	// Every sheet holds a full canvas in memory, so only a bounded number of
+	// them is processed at once.
+	var wt sync.WaitGroup
+	layouts := make([]*sheetLayout, len(sheets))
+	queue := make(chan int)
 
-			if debug {
-				fmt.Printf("Tiled image #%d has been written ('%s')\n", nt, name)
+	for i := 0; i < jobs; i++ {
+		wt.Add(1)
+
+		go func() {
+			defer wt.Done()
+
+			for i := range queue {
+				layouts[i] = compose(sheets[i])
 			}
+		}()
+	}
 
-			wt.Done()
-		}(i, images[a:b])
+	for i := range sheets {
+		// Without -keep-going, sheets being processed are finished but no new
+		// sheets are started after an error.
+		if !keepGoing && fails.len() > 0 {
+			break
+		}
+
+		queue <- i
 	}
 
+	close(queue)
 	wt.Wait()
 
+	if manifest != "" && manifest != "sheet" && !dryrun {
+		written := make([]*sheetLayout, 0, len(layouts))
+
+		for _, l := range layouts {
+			if l != nil {
+				written = append(written, l)
+			}
+		}
+
+		if err = writeManifest(out, manifest, written); err != nil {
+			fails.add(manifest, "", err)
+		}
+	}
+
+	if err = out.Close(); err != nil {
+		fails.add(archive, "", err)
+	}
+
+	in.close()
+
 	if verbose {
-		fmt.Println("Used options:")
-
-		fmt.Printf("  Reverse mode: %v\n", reverse)
-		fmt.Printf("  Name: %s\n", output)
-		fmt.Printf("  Size: %s\n", size)
-		fmt.Printf("  Background color: %s\n", bg)
-		fmt.Printf("  Tiles: %d\n", tiles)
-		fmt.Printf("    Resize mode: %s\n", format.Resize)
-		fmt.Printf("    Margin: %dpx\n", format.Margin)
-		fmt.Printf("    Alignment: %s\n", format.Align)
-		fmt.Printf("    Vertical alignment: %s\n", format.VAlign)
+		fmt.Fprintln(info, "Used options:")
+
+		fmt.Fprintf(info, "  Sort mode: %v\n", in.sorted)
+		fmt.Fprintf(info, "  Reverse mode: %v\n", in.reverse)
+		fmt.Fprintf(info, "  Recursive mode: %v\n", in.recursive)
+		fmt.Fprintf(info, "  Name: %s\n", output)
+		fmt.Fprintf(info, "  Archive: %s\n", archive)
+		fmt.Fprintf(info, "  Manifest: %s\n", manifest)
+		fmt.Fprintf(info, "  Size: %s\n", size)
+		fmt.Fprintf(info, "  Background color: %s\n", bg)
+		fmt.Fprintf(info, "  Jobs: %d\n", jobs)
+		fmt.Fprintf(info, "  Keep going: %v\n", keepGoing)
+		fmt.Fprintf(info, "  Skip bad images: %v\n", skipBad)
+		fmt.Fprintf(info, "  Tiles: %d\n", tiles)
+		fmt.Fprintf(info, "    Shape: %s\n", shape)
+		fmt.Fprintf(info, "  Repeat: %v\n", repeat)
+		fmt.Fprintf(info, "  Imposition: %s\n", impose)
+		fmt.Fprintf(info, "    Resize mode: %s\n", format.Resize)
+		fmt.Fprintf(info, "    Margin: %dpx\n", format.Margin)
+		fmt.Fprintf(info, "    Alignment: %s\n", format.Align)
+		fmt.Fprintf(info, "    Vertical alignment: %s\n", format.VAlign)
+		fmt.Fprintf(info, "    Caption: %s\n", captions.mode)
 
 		format = tile.DefaultFormat
 
 		if nt > 1 {
-			fmt.Printf(
+			fmt.Fprintf(
+				info,
 				"\n%d files generated from %d images in %s\n",
 				nt,
 				ni,
@@ -209,12 +785,52 @@ func main() {
 			)
 		}
 	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "Usage: %s [OPTIONS] IMAGE...\n", os.Args[0])
+	fmt.Fprintf(out, "       %s COMMAND [OPTIONS] IMAGE...\n\n", os.Args[0])
+	fmt.Fprintln(out, "Commands:")
+
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s\t%s\n", name, commands[name].help)
+	}
+
+	fmt.Fprintln(out, "\nOptions:")
+	flag.PrintDefaults()
+}
+
+// isFlagSet reports whether the flag with the given name was set in fs.
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
 }
 
-func closeFile(name string, file *os.File) {
+func closeFile(name string, file io.Closer) {
 	err := file.Close()
 
 	if err != nil {
-		log.Printf("Can't close the file '%v' -> %v\n", name)
+		log.Printf("Can't close the file '%v' -> %v\n", name, err)
 	}
 }
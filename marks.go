@@ -0,0 +1,230 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// markOptions are the printer marks drawn on top of the sheets.
+type markOptions struct {
+	crop, cut, registration bool
+
+	// width is the line width, if it is less than 1, it is computed from the
+	// sheet resolution.
+	width int
+	color color.Color
+}
+
+// enabled reports whether any mark should be drawn.
+func (o markOptions) enabled() bool {
+	return o.crop || o.cut || o.registration
+}
+
+// lineWidth returns the mark line width for sheets with dpi resolution.
+func (o markOptions) lineWidth(dpi int) int {
+	if o.width > 0 {
+		return o.width
+	}
+
+	if w := dpi / 150; w > 1 {
+		return w
+	}
+
+	return 1
+}
+
+// check returns an error if there is no room in page for the enabled crop or
+// registration marks of trims.
+func (o markOptions) check(page image.Rectangle, trims []image.Rectangle, dpi int) error {
+	if len(trims) == 0 {
+		return nil
+	}
+
+	w := o.lineWidth(dpi)
+
+	if o.crop && len(cropMarks(page, trims, dpi, w)) == 0 {
+		return errors.New("there is no room for crop marks between the tiles or in the page margins, use -margin")
+	}
+
+	if o.registration && len(registrationMarks(page, trims, dpi, w)) == 0 {
+		return errors.New("there is no room for registration marks in the page margins, use -margin")
+	}
+
+	return nil
+}
+
+// trimBoxes returns the trim boxes of tiles, the areas left by the f margins,
+// where sheets are cut. Captions below images are part of the trim box.
+func trimBoxes(tiles []image.Rectangle, f *tile.Format) []image.Rectangle {
+	m := &tile.Format{Margin: f.Margin}
+	trims := make([]image.Rectangle, len(tiles))
+
+	for i, t := range tiles {
+		trims[i], _ = m.Format(t, nil)
+	}
+
+	return trims
+}
+
+// drawMarks draws the enabled marks of the given trim boxes into dst, a sheet
+// with page size and dpi resolution.
+func drawMarks(dst draw.Image, page image.Rectangle, trims []image.Rectangle, dpi int, o markOptions) {
+	if len(trims) == 0 {
+		return
+	}
+
+	w := o.lineWidth(dpi)
+
+	if o.cut {
+		dash := dpi / 20
+
+		if dash < 4 {
+			dash = 4
+		}
+
+		for _, t := range trims {
+			tile.DrawLine(dst, t.Min, image.Pt(t.Max.X, t.Min.Y), w, o.color, dash)
+			tile.DrawLine(dst, image.Pt(t.Min.X, t.Max.Y), t.Max, w, o.color, dash)
+			tile.DrawLine(dst, t.Min, image.Pt(t.Min.X, t.Max.Y), w, o.color, dash)
+			tile.DrawLine(dst, image.Pt(t.Max.X, t.Min.Y), t.Max, w, o.color, dash)
+		}
+	}
+
+	if o.crop {
+		for _, m := range cropMarks(page, trims, dpi, w) {
+			tile.DrawLine(dst, m[0], m[1], w, o.color, 0)
+		}
+	}
+
+	if o.registration {
+		for _, m := range registrationMarks(page, trims, dpi, w) {
+			tile.DrawRegistration(dst, m.p, m.size, w, o.color)
+		}
+	}
+}
+
+// cropMarks returns the crop marks of trims that fit in page, between the
+// trims or in the page margins. Marks are shortened to stop before the other
+// trims and the page edges, and dropped if they get too short.
+func cropMarks(page image.Rectangle, trims []image.Rectangle, dpi, w int) [][2]image.Point {
+	// About 4mm long and 1mm away from the trim area.
+	size, off := dpi/6, dpi/24
+
+	if off < 2*w {
+		off = 2 * w
+	}
+
+	min := size / 3
+
+	if min < 2 {
+		min = 2
+	}
+
+	var marks [][2]image.Point
+
+	for _, t := range trims {
+		for _, m := range tile.CropMarks(t, size, off) {
+			if m, ok := fitMark(m, page, trims, w, min); ok {
+				marks = append(marks, m)
+			}
+		}
+	}
+
+	return marks
+}
+
+// fitMark returns m shortened from its end until it, with w width, is inside
+// page and doesn't overlap trims. It returns false if m would be shorter than
+// min.
+func fitMark(m [2]image.Point, page image.Rectangle, trims []image.Rectangle, w, min int) ([2]image.Point, bool) {
+	d := m[1].Sub(m[0])
+	n := d.X + d.Y
+
+	if n < 0 {
+		n = -n
+	}
+
+	if n == 0 {
+		return m, false
+	}
+
+	d = d.Div(n)
+
+	for l := n; l >= min; l-- {
+		end := m[0].Add(d.Mul(l))
+		r := image.Rectangle{m[0], end}.Canon().Inset(-w)
+
+		if r.In(page) && !overlapsAny(r, trims) {
+			return [2]image.Point{m[0], end}, true
+		}
+	}
+
+	return m, false
+}
+
+// registrationMark is a registration mark centered at p, with arms of size
+// length.
+type registrationMark struct {
+	p    image.Point
+	size int
+}
+
+// registrationMarks returns a registration mark centered in the page margin
+// at every side of the area covered by trims, if there is room for it.
+func registrationMarks(page image.Rectangle, trims []image.Rectangle, dpi, w int) []registrationMark {
+	area := trims[0]
+
+	for _, t := range trims[1:] {
+		area = area.Union(t)
+	}
+
+	size := dpi / 8
+	mid := image.Pt((area.Min.X+area.Max.X)/2, (area.Min.Y+area.Max.Y)/2)
+
+	sides := []struct {
+		margin int
+		p      image.Point
+	}{
+		{area.Min.Y - page.Min.Y, image.Pt(mid.X, (page.Min.Y+area.Min.Y)/2)},
+		{page.Max.Y - area.Max.Y, image.Pt(mid.X, (area.Max.Y+page.Max.Y)/2)},
+		{area.Min.X - page.Min.X, image.Pt((page.Min.X+area.Min.X)/2, mid.Y)},
+		{page.Max.X - area.Max.X, image.Pt((area.Max.X+page.Max.X)/2, mid.Y)},
+	}
+
+	var marks []registrationMark
+
+	for _, s := range sides {
+		n := size
+
+		if n > s.margin/2-w {
+			n = s.margin/2 - w
+		}
+
+		if n < 4 {
+			continue
+		}
+
+		marks = append(marks, registrationMark{s.p, n})
+	}
+
+	return marks
+}
+
+// overlapsAny reports whether r overlaps some of the given rectangles.
+func overlapsAny(r image.Rectangle, rs []image.Rectangle) bool {
+	for _, o := range rs {
+		if r.Overlaps(o) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,282 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/draw"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// histBins is the number of bins per channel of color histograms.
+const histBins = 4
+
+// runMosaic builds a photomosaic of a target image, filling every cell of a
+// grid with the input image that best matches its colors.
+func runMosaic(args []string) {
+	var (
+		in      inputFlags
+		verbose bool
+		cols    int
+		cell    int
+		size    string
+		bg      string
+		match   string
+		reuse   int
+		blend   float64
+		output  string
+	)
+
+	fs := flag.NewFlagSet("mosaic", flag.ExitOnError)
+	in.register(fs)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	fs.IntVar(&cols, "cols", 40, "Number of columns, rows keep the cells square")
+	fs.IntVar(&cell, "cell", 32, "Cells width and height, in pixels")
+
+	fs.StringVar(
+		&size,
+		"size",
+		"",
+		"Output size, the mosaic fits in it without overflow and -cell is "+
+			"ignored (see the main command -size)",
+	)
+
+	fs.StringVar(&bg, "bg", "black", "Background color")
+
+	fs.StringVar(
+		&match,
+		"match",
+		"average",
+		"Color matching, 'average' or 'histogram'",
+	)
+
+	fs.IntVar(&reuse, "reuse", 0, "Maximum uses of every image, 0 is unlimited")
+
+	fs.Float64Var(
+		&blend,
+		"blend",
+		0,
+		"Blending (0-1) of the cells toward the target colors",
+	)
+
+	fs.StringVar(&output, "o", "mosaic.jpg", "Output file")
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 2 {
+		log.Fatalln("A target image and at least 1 input image should be given")
+	}
+
+	if match != "average" && match != "histogram" {
+		log.Fatalf("Unknown color matching '%s'\n", match)
+	}
+
+	if blend < 0 || blend > 1 {
+		log.Fatalln("The blending should be between 0 and 1")
+	}
+
+	if cols < 1 {
+		log.Fatalln("There should be at least 1 column")
+	}
+
+	target, err := fileSource(fs.Arg(0)).decode()
+
+	if err != nil {
+		log.Fatalf("Can't decode the target image '%s' -> %v\n", fs.Arg(0), err)
+	}
+
+	tb := target.Bounds()
+	var area image.Rectangle
+
+	if size != "" {
+		page, ok := OutputSizes[size]
+
+		if !ok {
+			log.Fatalf("Unknown output size '%s'\n", size)
+		}
+
+		s := tile.ScaledSize(tb, page, "contain")
+		area = image.Rect(0, 0, s.X, s.Y)
+		cell = s.X / cols
+	} else {
+		area = image.Rect(0, 0, cols*cell, 0)
+	}
+
+	if cell < 1 {
+		log.Fatalln("The cells don't fit in the output size")
+	}
+
+	rows := int(float64(tb.Dy())*float64(area.Dx())/float64(tb.Dx())/float64(cell) + 0.5)
+
+	if rows < 1 {
+		rows = 1
+	}
+
+	if size == "" {
+		area.Max.Y = rows * cell
+	}
+
+	groups, err := in.groups(fs.Args()[1:])
+
+	if err != nil {
+		log.Fatalf("Can't read the given images -> %v\n", err)
+	}
+
+	var fails failures
+	var pool []mosaicTile
+	format := &tile.Format{Resize: "cover"}
+	thumb := image.Rect(0, 0, cell, cell)
+
+	for _, g := range groups {
+		for _, src := range g.images {
+			img, err := src.decode()
+
+			if err != nil {
+				fails.add(output, src.name, err)
+				continue
+			}
+
+			_, img = format.Format(thumb, img)
+			img = crop(img, thumb.Add(img.Bounds().Min))
+			pool = append(pool, mosaicTile{img: img, feature: colorFeature(img, img.Bounds(), match)})
+		}
+	}
+
+	in.close()
+
+	n := cols * rows
+
+	if len(pool) == 0 || reuse > 0 && reuse*len(pool) < n {
+		fails.add(output, "", fmt.Errorf("%d images can't fill %d cells", len(pool), n))
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+
+	scaled := image.NewRGBA(area)
+	draw.ApproxBiLinear.Scale(scaled, area, target, tb, draw.Src, nil)
+
+	t := tile.NewWithTiles(colornames.Map[bg], area, tile.Cells(area, cols, rows))
+	mask := &image.Uniform{color.Alpha{uint8(blend*0xff + 0.5)}}
+	uses := make([]int, len(pool))
+
+	// Cells are filled in a random (but repeatable) order, so limited images
+	// aren't always used up by the same area.
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		r := t.Tile(int64(i))
+		f := colorFeature(scaled, r, match)
+		best, dist := -1, math.Inf(1)
+
+		for j, pt := range pool {
+			if reuse > 0 && uses[j] >= reuse {
+				continue
+			}
+
+			if d := f.distance(pt.feature); d < dist {
+				best, dist = j, d
+			}
+		}
+
+		uses[best]++
+		t.DrawImageAt(pool[best].img, int64(i), format)
+
+		if blend > 0 {
+			draw.DrawMask(t.Image, r, scaled, r.Min, mask, image.ZP, draw.Over)
+		}
+	}
+
+	if verbose {
+		fmt.Printf(
+			"Mosaic of %dx%d cells from %d images written to '%s'\n",
+			cols,
+			rows,
+			len(pool),
+			output,
+		)
+	}
+
+	if err := writeImage(dirSink{}, output, t); err != nil {
+		fails.add(output, "", err)
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// mosaicTile is an input image of a mosaic, scaled to the cell size.
+type mosaicTile struct {
+	img     image.Image
+	feature feature
+}
+
+// feature describes the colors of an image area.
+type feature []float64
+
+// distance returns how different f and o are.
+func (f feature) distance(o feature) float64 {
+	d := 0.0
+
+	for i := range f {
+		x := f[i] - o[i]
+		d += x * x
+	}
+
+	return d
+}
+
+// colorFeature returns the feature of the r area of img according to match,
+// its average color or its normalized color histogram.
+func colorFeature(img image.Image, r image.Rectangle, match string) feature {
+	r = r.Intersect(img.Bounds())
+	var f feature
+
+	if match == "histogram" {
+		f = make(feature, histBins*histBins*histBins)
+	} else {
+		f = make(feature, 3)
+	}
+
+	n := float64(r.Dx() * r.Dy())
+
+	if n == 0 {
+		return f
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+
+			if match == "histogram" {
+				i := int(c.R)*histBins/256*histBins*histBins +
+					int(c.G)*histBins/256*histBins +
+					int(c.B)*histBins/256
+
+				f[i]++
+				continue
+			}
+
+			f[0] += float64(c.R)
+			f[1] += float64(c.G)
+			f[2] += float64(c.B)
+		}
+	}
+
+	for i := range f {
+		f[i] /= n
+	}
+
+	return f
+}
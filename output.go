@@ -0,0 +1,260 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputName returns the name of the nth output file of the g group using
+// the tmpl template. Every "{group}" in tmpl is replaced by the group name, if
+// tmpl doesn't reference the group, the output file is placed in a
+// subdirectory named after the group, next to the template location.
+func outputName(tmpl, g string, n int64) string {
+	if !strings.Contains(tmpl, "{group}") {
+		tmpl = filepath.Join(filepath.Dir(tmpl), "{group}", filepath.Base(tmpl))
+	}
+
+	return expandName(tmpl, map[string]string{"group": g}, n)
+}
+
+// expandName returns the tmpl template with every "{key}" replaced by its
+// value in vars and the %d verb replaced by n, if any.
+func expandName(tmpl string, vars map[string]string, n int64) string {
+	verbs := strings.Contains(tmpl, "%")
+
+	for k, v := range vars {
+		if verbs {
+			v = strings.Replace(v, "%", "%%", -1)
+		}
+
+		tmpl = strings.Replace(tmpl, "{"+k+"}", v, -1)
+	}
+
+	if verbs {
+		tmpl = fmt.Sprintf(tmpl, n)
+	}
+
+	return filepath.Clean(tmpl)
+}
+
+// encodeImage writes img to w, encoded according to the extension of name.
+// PNG is used for ".png", GIF for ".gif" and JPEG for anything else.
+func encodeImage(w io.Writer, img image.Image, name string) error {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png":
+		return png.Encode(w, img)
+	case ".gif":
+		return gif.Encode(w, img, nil)
+	}
+
+	return jpeg.Encode(w, img, nil)
+}
+
+// writeImage writes img to the output file with the given name (see
+// encodeImage).
+func writeImage(out sink, name string, img image.Image) error {
+	w, err := out.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	err = encodeImage(w, img, name)
+
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// writeSheet writes the img tiled image to the output file with the given
+// name. Sheets are always encoded as JPEG, whatever their extension.
+func writeSheet(out sink, name string, img image.Image) error {
+	w, err := out.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	err = jpeg.Encode(w, img, nil)
+
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// sink is a destination for output files.
+type sink interface {
+	// Create returns a writer for the output file with the given name, the
+	// file is complete once the writer is closed.
+	Create(name string) (io.WriteCloser, error)
+
+	// Close flushes any pending data.
+	Close() error
+}
+
+// newSink returns a sink for the archive at p. If p is empty, output files are
+// written to the file system, if it is "-", they are written as a tar stream
+// to the standard output.
+func newSink(p string) (sink, error) {
+	if p == "" {
+		return dirSink{}, nil
+	}
+
+	if p == "-" {
+		return newArchiveSink("tar", os.Stdout), nil
+	}
+
+	f := archiveFormat(p)
+
+	if f == "" {
+		return nil, fmt.Errorf("unsupported archive format '%s'", p)
+	}
+
+	file, err := os.Create(filepath.Clean(p))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newArchiveSink(f, file), nil
+}
+
+// dirSink writes output files to the file system, creating their parent
+// directories as needed.
+type dirSink struct{}
+
+func (dirSink) Create(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.Create(name)
+}
+
+func (dirSink) Close() error {
+	return nil
+}
+
+// archiveSink writes output files as entries of an archive. Since entries
+// must be written one at a time, they are buffered in memory until closed.
+type archiveSink struct {
+	mu sync.Mutex
+	w  io.Writer
+
+	zw *zip.Writer
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+// newArchiveSink returns a sink that writes an archive of the f format (see
+// ArchiveExts) to w. If w is an io.Closer, it is closed with the sink.
+func newArchiveSink(f string, w io.Writer) *archiveSink {
+	s := &archiveSink{w: w}
+
+	switch f {
+	case "zip":
+		s.zw = zip.NewWriter(w)
+	case "tgz":
+		s.gz = gzip.NewWriter(w)
+		s.tw = tar.NewWriter(s.gz)
+	default:
+		s.tw = tar.NewWriter(w)
+	}
+
+	return s
+}
+
+func (s *archiveSink) Create(name string) (io.WriteCloser, error) {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+	return &archiveEntry{s: s, name: name}, nil
+}
+
+func (s *archiveSink) Close() error {
+	var err error
+
+	if s.zw != nil {
+		err = s.zw.Close()
+	} else {
+		err = s.tw.Close()
+
+		if s.gz != nil && err == nil {
+			err = s.gz.Close()
+		}
+	}
+
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// write writes an entry into the archive.
+func (s *archiveSink) write(name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.zw != nil {
+		w, err := s.zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Store,
+			Modified: time.Now(),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		return err
+	}
+
+	err := s.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     int64(len(data)),
+		Mode:     0644,
+		ModTime:  time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.tw.Write(data)
+	return err
+}
+
+// archiveEntry is an archive entry being written.
+type archiveEntry struct {
+	bytes.Buffer
+
+	s    *archiveSink
+	name string
+}
+
+func (e *archiveEntry) Close() error {
+	return e.s.write(e.name, e.Bytes())
+}
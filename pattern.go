@@ -0,0 +1,147 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/draw"
+
+	"github.com/ntrrg/tiler/pkg/pattern"
+)
+
+// runPattern repeats the given images to fill a sheet as a pattern.
+func runPattern(args []string) {
+	var (
+		verbose  bool
+		size     string
+		bg       string
+		repeat   string
+		width    string
+		margin   string
+		seamless int
+		output   string
+	)
+
+	fs := flag.NewFlagSet("pattern", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	fs.StringVar(&size, "size", "letter300", "Sheet size")
+	fs.StringVar(&bg, "bg", "white", "Sheet background color")
+
+	fs.StringVar(
+		&repeat,
+		"repeat",
+		pattern.Straight,
+		"Repeat, 'straight', 'half-drop', 'brick' or 'mirror'",
+	)
+
+	fs.StringVar(
+		&width,
+		"width",
+		"",
+		"Motif width (px, in, cm, mm or pt), its height keeps the aspect "+
+			"ratio, the size of the first image is used if empty",
+	)
+
+	fs.StringVar(&margin, "margin", "0", "Sheet margin (px, in, cm, mm or pt)")
+
+	fs.IntVar(
+		&seamless,
+		"seamless",
+		0,
+		"Blend this many pixels of the image edges, so textures repeat "+
+			"without visible seams",
+	)
+
+	fs.StringVar(&output, "o", "pattern.jpg", "Output file")
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 1 {
+		log.Fatalln("At least 1 image should be given")
+	}
+
+	sheet, ok := OutputSizes[size]
+
+	if !ok {
+		log.Fatalf("Unknown sheet size '%s'\n", size)
+	}
+
+	dpi := OutputDPI[size]
+	m, err := parseLength(margin, dpi)
+
+	if err != nil {
+		log.Fatalf("Invalid margin -> %v\n", err)
+	}
+
+	motifs := make([]image.Image, fs.NArg())
+
+	for i, p := range fs.Args() {
+		img, err := fileSource(p).decode()
+
+		if err != nil {
+			log.Fatalf("Can't decode the image '%s' -> %v\n", p, err)
+		}
+
+		if seamless > 0 {
+			img = pattern.Seamless(img, image.Pt(seamless, seamless))
+		}
+
+		motifs[i] = img
+	}
+
+	ms := motifs[0].Bounds().Size()
+
+	if width != "" {
+		w, err := parseLength(width, dpi)
+
+		if err != nil {
+			log.Fatalf("Invalid motif width -> %v\n", err)
+		}
+
+		if w < 1 {
+			log.Fatalln("The motif width should be positive")
+		}
+
+		ms = image.Pt(w, ms.Y*w/ms.X)
+	}
+
+	// Every motif is scaled to the same size, so they fit in the repeat.
+	for i, img := range motifs {
+		if img.Bounds().Size() == ms {
+			continue
+		}
+
+		dst := image.NewRGBA(image.Rectangle{Max: ms})
+		draw.BiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+		motifs[i] = dst
+	}
+
+	dst := image.NewRGBA(sheet)
+	draw.Draw(dst, sheet, &image.Uniform{colornames.Map[bg]}, image.ZP, draw.Src)
+
+	if err := pattern.Fill(dst, sheet.Inset(m), motifs, repeat); err != nil {
+		log.Fatalf("Can't fill the pattern -> %v\n", err)
+	}
+
+	if verbose {
+		fmt.Printf(
+			"%s pattern of %d motifs of %dx%d px written to '%s'\n",
+			repeat,
+			len(motifs),
+			ms.X,
+			ms.Y,
+			output,
+		)
+	}
+
+	if err := writeImage(dirSink{}, output, dst); err != nil {
+		log.Fatalf("Can't write the pattern '%s' -> %v\n", output, err)
+	}
+}
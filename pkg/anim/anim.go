@@ -0,0 +1,246 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+// Package anim provides conversion between animated GIFs and sequences of
+// full frames.
+package anim
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// Supported palettes.
+const (
+	Adaptive = "adaptive"
+	Plan9    = "plan9"
+	WebSafe  = "websafe"
+)
+
+// Frames returns the fully composited frames of g, as they are shown by a
+// viewer, respecting the disposal method of every frame.
+func Frames(g *gif.GIF) []*image.RGBA {
+	r := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+
+	if r.Empty() {
+		for _, f := range g.Image {
+			r = r.Union(f.Bounds())
+		}
+	}
+
+	canvas := image.NewRGBA(r)
+	frames := make([]*image.RGBA, len(g.Image))
+
+	for i, f := range g.Image {
+		disposal := byte(gif.DisposalNone)
+
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var prev *image.RGBA
+
+		if disposal == gif.DisposalPrevious {
+			prev = clone(canvas)
+		}
+
+		draw.Draw(canvas, f.Bounds(), f, f.Bounds().Min, draw.Over)
+		frames[i] = clone(canvas)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			// Viewers clear to transparent instead of the background color.
+			draw.Draw(canvas, f.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prev
+		}
+	}
+
+	return frames
+}
+
+// Options are the GIF encoding options.
+type Options struct {
+	// Delay is the delay between frames, in hundredths of a second.
+	Delay int
+
+	// LoopCount is the number of times the animation is repeated, 0 means
+	// forever and -1 shows it only once.
+	LoopCount int
+
+	// Palette is the name of the palette, Adaptive, Plan9 or WebSafe.
+	Palette string
+
+	// Dither enables Floyd-Steinberg error diffusion.
+	Dither bool
+}
+
+// New returns an animated GIF with the given frames.
+func New(frames []image.Image, o *Options) *gif.GIF {
+	p, transparent := Palette(frames, o.Palette)
+
+	g := &gif.GIF{
+		Image:     make([]*image.Paletted, len(frames)),
+		Delay:     make([]int, len(frames)),
+		Disposal:  make([]byte, len(frames)),
+		LoopCount: o.LoopCount,
+	}
+
+	var drawer draw.Drawer = draw.Src
+
+	if o.Dither {
+		drawer = draw.FloydSteinberg
+	}
+
+	for i, f := range frames {
+		b := f.Bounds()
+		dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), p)
+		drawer.Draw(dst, dst.Bounds(), f, b.Min)
+		g.Image[i] = dst
+		g.Delay[i] = o.Delay
+		g.Disposal[i] = gif.DisposalNone
+
+		// Frames are complete, so transparent areas must not show the
+		// previous frame.
+		if transparent {
+			g.Disposal[i] = gif.DisposalBackground
+		}
+
+		if r := dst.Bounds(); r.Dx() > g.Config.Width || r.Dy() > g.Config.Height {
+			g.Config.Width, g.Config.Height = r.Dx(), r.Dy()
+		}
+	}
+
+	return g
+}
+
+// Palette returns the palette with the given name for frames and whether it
+// has a transparent color. Unknown names use the Adaptive palette.
+func Palette(frames []image.Image, name string) (color.Palette, bool) {
+	var p color.Palette
+
+	switch name {
+	case Plan9:
+		p = append(p, palette.Plan9...)
+	case WebSafe:
+		p = append(p, palette.WebSafe...)
+	default:
+		return adaptive(frames)
+	}
+
+	if !hasTransparency(frames) {
+		return p, false
+	}
+
+	// The last color is replaced, since palettes can't have more than 256.
+	if len(p) == 256 {
+		p = p[:255]
+	}
+
+	return append(color.Palette{color.Transparent}, p...), true
+}
+
+// adaptive returns a palette with the most used colors of frames, colors are
+// grouped by their 5 most significant bits.
+func adaptive(frames []image.Image) (color.Palette, bool) {
+	type bucket struct {
+		r, g, b, n int
+	}
+
+	buckets := make(map[int]*bucket)
+	transparent := false
+
+	for _, f := range frames {
+		b := f.Bounds()
+
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, a := f.At(x, y).RGBA()
+
+				if a < 0x8000 {
+					transparent = true
+					continue
+				}
+
+				// Unpremultiplied 8 bit values.
+				r, g, bl = r*0xff/a, g*0xff/a, bl*0xff/a
+				k := int(r>>3)<<10 | int(g>>3)<<5 | int(bl>>3)
+				c, ok := buckets[k]
+
+				if !ok {
+					c = &bucket{}
+					buckets[k] = c
+				}
+
+				c.r += int(r)
+				c.g += int(g)
+				c.b += int(bl)
+				c.n++
+			}
+		}
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+
+	for _, c := range buckets {
+		sorted = append(sorted, c)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].n > sorted[j].n
+	})
+
+	n := 256
+	var p color.Palette
+
+	if transparent {
+		p = append(p, color.Transparent)
+		n--
+	}
+
+	for i := 0; i < len(sorted) && i < n; i++ {
+		c := sorted[i]
+
+		p = append(p, color.RGBA{
+			uint8(c.r / c.n),
+			uint8(c.g / c.n),
+			uint8(c.b / c.n),
+			0xff,
+		})
+	}
+
+	if len(p) == 0 {
+		p = append(p, color.Black)
+	}
+
+	return p, transparent
+}
+
+// hasTransparency reports whether some pixel of frames is mostly transparent.
+func hasTransparency(frames []image.Image) bool {
+	for _, f := range frames {
+		b := f.Bounds()
+
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if _, _, _, a := f.At(x, y).RGBA(); a < 0x8000 {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// clone returns a copy of img.
+func clone(img *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	copy(dst.Pix, img.Pix)
+	return dst
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package anim
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	var (
+		transparent = color.RGBA{}
+		red         = color.RGBA{0xff, 0, 0, 0xff}
+		green       = color.RGBA{0, 0xff, 0, 0xff}
+		blue        = color.RGBA{0, 0, 0xff, 0xff}
+		white       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	)
+
+	p := color.Palette{transparent, red, green, blue, white}
+
+	frame := func(r image.Rectangle, c color.Color) *image.Paletted {
+		f := image.NewPaletted(r, p)
+
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				f.Set(x, y, c)
+			}
+		}
+
+		return f
+	}
+
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			frame(image.Rect(0, 0, 4, 4), red),
+			frame(image.Rect(0, 0, 2, 2), blue),
+			frame(image.Rect(2, 2, 4, 4), green),
+			frame(image.Rect(3, 0, 4, 1), white),
+		},
+		Disposal: []byte{
+			gif.DisposalNone,
+			gif.DisposalBackground,
+			gif.DisposalPrevious,
+			gif.DisposalNone,
+		},
+		Config: image.Config{Width: 4, Height: 4},
+	}
+
+	cases := []struct {
+		frame int
+		p     image.Point
+		want  color.RGBA
+	}{
+		{0, image.Pt(0, 0), red},
+		{0, image.Pt(3, 3), red},
+
+		// Drawn over the first frame.
+		{1, image.Pt(0, 0), blue},
+		{1, image.Pt(3, 3), red},
+
+		// The second frame area is cleared.
+		{2, image.Pt(0, 0), transparent},
+		{2, image.Pt(3, 3), green},
+		{2, image.Pt(3, 0), red},
+
+		// The third frame is undone.
+		{3, image.Pt(0, 0), transparent},
+		{3, image.Pt(3, 3), red},
+		{3, image.Pt(3, 0), white},
+	}
+
+	frames := Frames(g)
+
+	if len(frames) != len(g.Image) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(g.Image))
+	}
+
+	for i, f := range frames {
+		if b := f.Bounds(); b != image.Rect(0, 0, 4, 4) {
+			t.Errorf("frame %d: got bounds %v, want the canvas", i, b)
+		}
+	}
+
+	for _, c := range cases {
+		if got := frames[c.frame].RGBAAt(c.p.X, c.p.Y); got != c.want {
+			t.Errorf("frame %d at %v: got %v, want %v", c.frame, c.p, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+// Package exif provides minimal EXIF metadata reading from JPEG images.
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Errors returned by Decode.
+var (
+	ErrNoExif    = errors.New("exif: no EXIF data found")
+	ErrNotJPEG   = errors.New("exif: not a JPEG image")
+	ErrMalformed = errors.New("exif: malformed EXIF data")
+)
+
+// TimeLayout is the layout used by EXIF date fields.
+const TimeLayout = "2006:01:02 15:04:05"
+
+// Tags used by Decode.
+const (
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+)
+
+// Exif is the subset of EXIF metadata used by tiler.
+type Exif struct {
+	// Orientation is the EXIF orientation (1-8) of the image, 0 if absent.
+	Orientation int
+
+	// DateTime is the date the image was taken, or if absent, the date it was
+	// last modified.
+	DateTime time.Time
+}
+
+// Decode reads the EXIF metadata of the JPEG image from r, only the data
+// before the image scan is read.
+func Decode(r io.Reader) (*Exif, error) {
+	br := bufio.NewReader(r)
+	var marker [2]byte
+
+	if _, err := io.ReadFull(br, marker[:]); err != nil {
+		return nil, ErrNotJPEG
+	}
+
+	if marker != [2]byte{0xFF, 0xD8} {
+		return nil, ErrNotJPEG
+	}
+
+	for {
+		if _, err := io.ReadFull(br, marker[:]); err != nil {
+			return nil, ErrNoExif
+		}
+
+		if marker[0] != 0xFF {
+			return nil, ErrMalformed
+		}
+
+		// Start of scan and end of image, no metadata past this point.
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, ErrNoExif
+		}
+
+		var n uint16
+
+		if err := binary.Read(br, binary.BigEndian, &n); err != nil || n < 2 {
+			return nil, ErrMalformed
+		}
+
+		seg := make([]byte, n-2)
+
+		if _, err := io.ReadFull(br, seg); err != nil {
+			return nil, ErrMalformed
+		}
+
+		if marker[1] == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return parseTIFF(seg[6:])
+		}
+	}
+}
+
+// parseTIFF parses the TIFF structure that holds EXIF data.
+func parseTIFF(b []byte) (*Exif, error) {
+	if len(b) < 8 {
+		return nil, ErrMalformed
+	}
+
+	var bo binary.ByteOrder
+
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return nil, ErrMalformed
+	}
+
+	x := new(Exif)
+	var original, modified string
+
+	ifd0, err := readIFD(b, bo, bo.Uint32(b[4:]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := ifd0[tagOrientation]; ok {
+		x.Orientation = int(bo.Uint16(e[8:]))
+	}
+
+	if e, ok := ifd0[tagDateTime]; ok {
+		modified = readString(b, bo, e)
+	}
+
+	if e, ok := ifd0[tagExifIFD]; ok {
+		sub, err := readIFD(b, bo, bo.Uint32(e[8:]))
+
+		if err == nil {
+			if e, ok := sub[tagDateTimeOriginal]; ok {
+				original = readString(b, bo, e)
+			}
+		}
+	}
+
+	for _, s := range []string{original, modified} {
+		if t, err := time.Parse(TimeLayout, s); err == nil {
+			x.DateTime = t
+			break
+		}
+	}
+
+	return x, nil
+}
+
+// readIFD returns the raw 12 bytes entries of the IFD at off, indexed by tag.
+func readIFD(b []byte, bo binary.ByteOrder, off uint32) (map[uint16][]byte, error) {
+	if int64(off)+2 > int64(len(b)) {
+		return nil, ErrMalformed
+	}
+
+	n := int(bo.Uint16(b[off:]))
+	start := int(off) + 2
+
+	if start+n*12 > len(b) {
+		return nil, ErrMalformed
+	}
+
+	entries := make(map[uint16][]byte, n)
+
+	for i := 0; i < n; i++ {
+		e := b[start+i*12 : start+(i+1)*12]
+		entries[bo.Uint16(e)] = e
+	}
+
+	return entries, nil
+}
+
+// readString returns the value of an ASCII entry.
+func readString(b []byte, bo binary.ByteOrder, e []byte) string {
+	n := bo.Uint32(e[4:])
+	v := e[8:12]
+
+	if n > 4 {
+		off := bo.Uint32(e[8:])
+
+		if int64(off)+int64(n) > int64(len(b)) {
+			return ""
+		}
+
+		v = b[off : off+n]
+	} else {
+		v = v[:n]
+	}
+
+	return string(bytes.TrimRight(v, "\x00 "))
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	date := time.Date(2018, 7, 4, 10, 30, 0, 0, time.UTC)
+	le, be := binary.LittleEndian, binary.BigEndian
+
+	cases := []struct {
+		name string
+		data []byte
+		want Exif
+		err  error
+	}{
+		{"empty", nil, Exif{}, ErrNotJPEG},
+		{"png", []byte("\x89PNG\r\n\x1a\n"), Exif{}, ErrNotJPEG},
+		{"truncated", []byte{0xFF, 0xD8}, Exif{}, ErrNoExif},
+		{"no exif", jpeg(segment(0xE0, []byte("JFIF\x00"))), Exif{}, ErrNoExif},
+		{"bad marker", []byte{0xFF, 0xD8, 0x00, 0xE1}, Exif{}, ErrMalformed},
+		{"bad length", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01}, Exif{}, ErrMalformed},
+		{"bad tiff", jpeg(app1([]byte("XX\x00\x2a\x00\x00\x00\x08"))), Exif{}, ErrMalformed},
+		{"bad ifd", jpeg(app1([]byte("II\x2a\x00\xff\x00\x00\x00"))), Exif{}, ErrMalformed},
+		{
+			"little endian",
+			jpeg(app1(tiff(le, 6, "2018:07:04 10:30:00", ""))),
+			Exif{Orientation: 6, DateTime: date},
+			nil,
+		},
+		{
+			"big endian",
+			jpeg(app1(tiff(be, 3, "2018:07:04 10:30:00", ""))),
+			Exif{Orientation: 3, DateTime: date},
+			nil,
+		},
+		{
+			"original date",
+			jpeg(app1(tiff(le, 1, "2020:01:01 00:00:00", "2018:07:04 10:30:00"))),
+			Exif{Orientation: 1, DateTime: date},
+			nil,
+		},
+		{
+			"invalid date",
+			jpeg(app1(tiff(be, 0, "yesterday", ""))),
+			Exif{},
+			nil,
+		},
+		{
+			"after other segments",
+			jpeg(segment(0xE0, []byte("JFIF\x00")), app1(tiff(le, 8, "", ""))),
+			Exif{Orientation: 8},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		x, err := Decode(bytes.NewReader(c.data))
+
+		if err != c.err {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if x.Orientation != c.want.Orientation || !x.DateTime.Equal(c.want.DateTime) {
+			t.Errorf("%s: got %+v, want %+v", c.name, *x, c.want)
+		}
+	}
+}
+
+// jpeg returns a JPEG image header with the given segments.
+func jpeg(segments ...[]byte) []byte {
+	b := []byte{0xFF, 0xD8}
+
+	for _, s := range segments {
+		b = append(b, s...)
+	}
+
+	// Start of scan.
+	return append(b, 0xFF, 0xDA, 0x00, 0x02)
+}
+
+// segment returns a JPEG segment with the given marker and data.
+func segment(marker byte, data []byte) []byte {
+	b := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], uint16(len(data)+2))
+	return append(b, data...)
+}
+
+// app1 returns an EXIF segment with the given TIFF data.
+func app1(data []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), data...))
+}
+
+// tiff returns TIFF data with the given orientation and dates, zero values are
+// omitted. The original date is stored in the EXIF sub IFD.
+func tiff(bo binary.ByteOrder, orientation int, modified, original string) []byte {
+	const ifd0 = 8
+	var entries [][]byte
+
+	entry := func(tag, typ uint16, count uint32) []byte {
+		e := make([]byte, 12)
+		bo.PutUint16(e, tag)
+		bo.PutUint16(e[2:], typ)
+		bo.PutUint32(e[4:], count)
+		entries = append(entries, e)
+		return e
+	}
+
+	if orientation > 0 {
+		bo.PutUint16(entry(tagOrientation, 3, 1)[8:], uint16(orientation))
+	}
+
+	var strs []byte
+	var refs [][]byte
+
+	if modified != "" {
+		refs = append(refs, entry(tagDateTime, 2, uint32(len(modified)+1)))
+		strs = append(strs, modified+"\x00"...)
+	}
+
+	sub := entry(tagExifIFD, 4, 1)
+
+	// IFD0, the sub IFD with one entry and then the strings.
+	subOff := ifd0 + 2 + len(entries)*12 + 4
+	strOff := subOff + 2 + 12 + 4
+
+	b := make([]byte, strOff)
+	copy(b, "II")
+
+	if bo == binary.BigEndian {
+		copy(b, "MM")
+	}
+
+	bo.PutUint16(b[2:], 42)
+	bo.PutUint32(b[4:], ifd0)
+	bo.PutUint32(sub[8:], uint32(subOff))
+
+	for _, r := range refs {
+		bo.PutUint32(r[8:], uint32(strOff))
+	}
+
+	bo.PutUint16(b[ifd0:], uint16(len(entries)))
+
+	for i, e := range entries {
+		copy(b[ifd0+2+i*12:], e)
+	}
+
+	if original != "" {
+		bo.PutUint16(b[subOff:], 1)
+		e := b[subOff+2:]
+		bo.PutUint16(e, tagDateTimeOriginal)
+		bo.PutUint16(e[2:], 2)
+		bo.PutUint32(e[4:], uint32(len(original)+1))
+		bo.PutUint32(e[8:], uint32(strOff+len(strs)))
+		strs = append(strs, original+"\x00"...)
+	}
+
+	return append(b, strs...)
+}
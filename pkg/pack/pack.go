@@ -0,0 +1,237 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+// Package pack provides rectangle bin packing for building texture atlases,
+// using the MaxRects algorithm with the best short side fit heuristic.
+package pack
+
+import (
+	"errors"
+	"image"
+	"sort"
+)
+
+// ErrTooBig is returned when the rectangles don't fit in the maximum size.
+var ErrTooBig = errors.New("pack: rectangles don't fit in the maximum size")
+
+// Pack returns the position of every size in a bin with at most max size,
+// along with the size of the used area. The bin starts with the smallest
+// power of two that may hold all the sizes and grows until they fit. If pot
+// is true, the returned size is a power of two, so the bin is limited to the
+// biggest power of two that isn't bigger than max.
+func Pack(sizes []image.Point, max image.Point, pot bool) ([]image.Point, image.Point, error) {
+	if pot {
+		max = image.Pt(floorPow2(max.X), floorPow2(max.Y))
+	}
+
+	area := 0
+	need := image.Point{}
+
+	for _, s := range sizes {
+		area += s.X * s.Y
+
+		if s.X > need.X {
+			need.X = s.X
+		}
+
+		if s.Y > need.Y {
+			need.Y = s.Y
+		}
+	}
+
+	bin := image.Pt(1, 1)
+
+	for bin.X < need.X || bin.X*bin.X < area {
+		bin.X *= 2
+	}
+
+	for bin.Y < need.Y || bin.X*bin.Y < area {
+		bin.Y *= 2
+	}
+
+	for {
+		if bin.X > max.X {
+			bin.X = max.X
+		}
+
+		if bin.Y > max.Y {
+			bin.Y = max.Y
+		}
+
+		if pos, ok := NewBin(bin.X, bin.Y).InsertAll(sizes); ok {
+			used := image.Point{}
+
+			for i, p := range pos {
+				e := p.Add(sizes[i])
+				used.X, used.Y = maxInt(used.X, e.X), maxInt(used.Y, e.Y)
+			}
+
+			if pot {
+				used = bin
+			}
+
+			return pos, used, nil
+		}
+
+		if bin.X >= max.X && bin.Y >= max.Y {
+			return nil, image.Point{}, ErrTooBig
+		}
+
+		// Grows the shortest side.
+		if bin.X <= bin.Y && bin.X < max.X || bin.Y >= max.Y {
+			bin.X *= 2
+		} else {
+			bin.Y *= 2
+		}
+	}
+}
+
+// Bin is a MaxRects bin.
+type Bin struct {
+	free []image.Rectangle
+}
+
+// NewBin returns an empty bin with w width and h height.
+func NewBin(w, h int) *Bin {
+	return &Bin{free: []image.Rectangle{image.Rect(0, 0, w, h)}}
+}
+
+// InsertAll inserts every size, biggest first, and returns their positions in
+// the given order. It returns false if some size doesn't fit.
+func (b *Bin) InsertAll(sizes []image.Point) ([]image.Point, bool) {
+	order := make([]int, len(sizes))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, c := sizes[order[i]], sizes[order[j]]
+		return maxInt(a.X, a.Y) > maxInt(c.X, c.Y)
+	})
+
+	pos := make([]image.Point, len(sizes))
+
+	for _, i := range order {
+		p, ok := b.Insert(sizes[i])
+
+		if !ok {
+			return nil, false
+		}
+
+		pos[i] = p
+	}
+
+	return pos, true
+}
+
+// Insert places a rectangle with s size and returns its position. It returns
+// false if it doesn't fit.
+func (b *Bin) Insert(s image.Point) (image.Point, bool) {
+	if s.X <= 0 || s.Y <= 0 {
+		return image.Point{}, true
+	}
+
+	best := -1
+	bestShort, bestLong := 0, 0
+
+	for i, f := range b.free {
+		if f.Dx() < s.X || f.Dy() < s.Y {
+			continue
+		}
+
+		dx, dy := f.Dx()-s.X, f.Dy()-s.Y
+		short, long := minInt(dx, dy), maxInt(dx, dy)
+
+		if best < 0 || short < bestShort || short == bestShort && long < bestLong {
+			best, bestShort, bestLong = i, short, long
+		}
+	}
+
+	if best < 0 {
+		return image.Point{}, false
+	}
+
+	used := image.Rectangle{b.free[best].Min, b.free[best].Min.Add(s)}
+	b.split(used)
+	return used.Min, true
+}
+
+// split removes used from the free rectangles, replacing every intersected
+// free rectangle with its maximal non-intersecting parts.
+func (b *Bin) split(used image.Rectangle) {
+	var free []image.Rectangle
+
+	for _, f := range b.free {
+		if !f.Overlaps(used) {
+			free = append(free, f)
+			continue
+		}
+
+		if used.Min.X > f.Min.X {
+			free = append(free, image.Rect(f.Min.X, f.Min.Y, used.Min.X, f.Max.Y))
+		}
+
+		if used.Max.X < f.Max.X {
+			free = append(free, image.Rect(used.Max.X, f.Min.Y, f.Max.X, f.Max.Y))
+		}
+
+		if used.Min.Y > f.Min.Y {
+			free = append(free, image.Rect(f.Min.X, f.Min.Y, f.Max.X, used.Min.Y))
+		}
+
+		if used.Max.Y < f.Max.Y {
+			free = append(free, image.Rect(f.Min.X, used.Max.Y, f.Max.X, f.Max.Y))
+		}
+	}
+
+	// Prunes free rectangles contained by others.
+	b.free = make([]image.Rectangle, 0, len(free))
+
+	for i, f := range free {
+		contained := false
+
+		for j, g := range free {
+			if i != j && f.In(g) && (f != g || j < i) {
+				contained = true
+				break
+			}
+		}
+
+		if !contained {
+			b.free = append(b.free, f)
+		}
+	}
+}
+
+// floorPow2 returns the biggest power of two that isn't bigger than n, or 0 if
+// n is less than 1.
+func floorPow2(n int) int {
+	if n < 1 {
+		return 0
+	}
+
+	p := 1
+
+	for p <= n/2 {
+		p *= 2
+	}
+
+	return p
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
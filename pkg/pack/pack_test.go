@@ -0,0 +1,96 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package pack
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	cases := []struct {
+		name  string
+		sizes []image.Point
+		max   image.Point
+		pot   bool
+		want  image.Point
+		err   error
+	}{
+		{"empty", nil, image.Pt(64, 64), false, image.Pt(0, 0), nil},
+		{"single", []image.Point{{30, 20}}, image.Pt(64, 64), false, image.Pt(30, 20), nil},
+		{"single pot", []image.Point{{30, 20}}, image.Pt(64, 64), true, image.Pt(32, 32), nil},
+		{"row", []image.Point{{10, 10}, {10, 10}, {10, 10}, {10, 10}}, image.Pt(20, 20), false, image.Pt(20, 20), nil},
+		{"too big", []image.Point{{65, 10}}, image.Pt(64, 64), false, image.Point{}, ErrTooBig},
+		{"too many", []image.Point{{40, 40}, {40, 40}}, image.Pt(64, 64), false, image.Point{}, ErrTooBig},
+		{"non pot max", []image.Point{{100, 100}}, image.Pt(100, 100), false, image.Pt(100, 100), nil},
+		{"pot max", []image.Point{{100, 100}}, image.Pt(100, 100), true, image.Point{}, ErrTooBig},
+		{"pot clamped", []image.Point{{200, 100}, {200, 100}}, image.Pt(300, 300), true, image.Pt(256, 256), nil},
+		{"pot limit", []image.Point{{200, 100}, {200, 100}, {200, 100}}, image.Pt(300, 300), true, image.Point{}, ErrTooBig},
+	}
+
+	for _, c := range cases {
+		pos, size, err := Pack(c.sizes, c.max, c.pot)
+
+		if err != c.err {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if size != c.want {
+			t.Errorf("%s: got size %v, want %v", c.name, size, c.want)
+		}
+
+		checkPositions(t, c.name, c.sizes, pos, size)
+	}
+}
+
+func TestFloorPow2(t *testing.T) {
+	cases := []struct {
+		n, want int
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 1},
+		{2, 2},
+		{3, 2},
+		{700, 512},
+		{1024, 1024},
+		{4097, 4096},
+	}
+
+	for _, c := range cases {
+		if got := floorPow2(c.n); got != c.want {
+			t.Errorf("floorPow2(%d) = %d, want %d", c.n, got, c.want)
+		}
+	}
+}
+
+// checkPositions reports rectangles that overlap or exceed the bin size.
+func checkPositions(t *testing.T, name string, sizes, pos []image.Point, bin image.Point) {
+	if len(pos) != len(sizes) {
+		t.Errorf("%s: got %d positions, want %d", name, len(pos), len(sizes))
+		return
+	}
+
+	rs := make([]image.Rectangle, len(sizes))
+	b := image.Rectangle{Max: bin}
+
+	for i, p := range pos {
+		rs[i] = image.Rectangle{p, p.Add(sizes[i])}
+
+		if !rs[i].In(b) {
+			t.Errorf("%s: %v is out of the %v bin", name, rs[i], bin)
+		}
+
+		for _, r := range rs[:i] {
+			if rs[i].Overlaps(r) {
+				t.Errorf("%s: %v overlaps %v", name, rs[i], r)
+			}
+		}
+	}
+}
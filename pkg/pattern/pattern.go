@@ -0,0 +1,177 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+// Package pattern provides repeating patterns generation.
+package pattern
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// Supported repeats.
+const (
+	// Straight places motifs in a regular grid.
+	Straight = "straight"
+
+	// HalfDrop shifts down every odd column by half the motif height.
+	HalfDrop = "half-drop"
+
+	// Brick shifts right every odd row by half the motif width.
+	Brick = "brick"
+
+	// Mirror flips horizontally every odd column and vertically every odd
+	// row, so neighbor motifs are reflections of each other.
+	Mirror = "mirror"
+)
+
+// Fill repeats motifs over the r area of dst using the given repeat. Motifs
+// are used in turns and all of them should have the same size.
+func Fill(dst draw.Image, r image.Rectangle, motifs []image.Image, repeat string) error {
+	if len(motifs) == 0 {
+		return fmt.Errorf("pattern: no motifs given")
+	}
+
+	switch repeat {
+	case Straight, HalfDrop, Brick, Mirror:
+	default:
+		return fmt.Errorf("pattern: unknown repeat '%s'", repeat)
+	}
+
+	s := motifs[0].Bounds().Size()
+
+	if s.X < 1 || s.Y < 1 {
+		return fmt.Errorf("pattern: empty motif")
+	}
+
+	var flipped [][4]image.Image
+
+	if repeat == Mirror {
+		flipped = make([][4]image.Image, len(motifs))
+
+		for i, m := range motifs {
+			flipped[i] = [4]image.Image{m, flip(m, true, false), flip(m, false, true), flip(m, true, true)}
+		}
+	}
+
+	// The first column and row start outside r, so shifted motifs cover its
+	// edges too.
+	for c := -1; c*s.X < r.Dx(); c++ {
+		for l := -1; l*s.Y < r.Dy(); l++ {
+			p := image.Pt(c*s.X, l*s.Y)
+
+			if repeat == HalfDrop && mod(c, 2) == 1 {
+				p.Y += s.Y / 2
+			}
+
+			if repeat == Brick && mod(l, 2) == 1 {
+				p.X += s.X / 2
+			}
+
+			i := mod(c+l, len(motifs))
+			m := motifs[i]
+
+			if repeat == Mirror {
+				m = flipped[i][mod(c, 2)+2*mod(l, 2)]
+			}
+
+			tr := image.Rectangle{p, p.Add(s)}.Add(r.Min).Intersect(r)
+
+			if tr.Empty() {
+				continue
+			}
+
+			sp := m.Bounds().Min.Add(tr.Min.Sub(r.Min.Add(p)))
+			draw.Draw(dst, tr, m, sp, draw.Over)
+		}
+	}
+
+	return nil
+}
+
+// Seamless returns a copy of img that can be repeated without visible edges.
+// The last band pixels of every side are cross faded into the opposite side,
+// so the result is smaller than img by band.
+func Seamless(img image.Image, band image.Point) *image.RGBA {
+	b := img.Bounds()
+
+	if band.X > b.Dx()/2 {
+		band.X = b.Dx() / 2
+	}
+
+	if band.Y > b.Dy()/2 {
+		band.Y = b.Dy() / 2
+	}
+
+	// Horizontal blending.
+	w, h := b.Dx()-band.X, b.Dy()
+	tmp := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(tmp, tmp.Bounds(), img, b.Min, draw.Src)
+
+	for x := 0; x < band.X; x++ {
+		t := float64(x) / float64(band.X)
+
+		for y := 0; y < h; y++ {
+			tmp.Set(x, y, lerp(img.At(b.Min.X+w+x, b.Min.Y+y), tmp.At(x, y), t))
+		}
+	}
+
+	// Vertical blending.
+	h -= band.Y
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), tmp, image.ZP, draw.Src)
+
+	for y := 0; y < band.Y; y++ {
+		t := float64(y) / float64(band.Y)
+
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, lerp(tmp.At(x, h+y), dst.At(x, y), t))
+		}
+	}
+
+	return dst
+}
+
+// flip returns a copy of img flipped horizontally and/or vertically.
+func flip(img image.Image, h, v bool) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			sx, sy := x, y
+
+			if h {
+				sx = b.Dx() - 1 - x
+			}
+
+			if v {
+				sy = b.Dy() - 1 - y
+			}
+
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// lerp returns the linear interpolation between a and b at t (0-1).
+func lerp(a, b color.Color, t float64) color.Color {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+
+	mix := func(x, y uint32) uint16 {
+		return uint16(float64(x)*(1-t) + float64(y)*t + 0.5)
+	}
+
+	return color.RGBA64{mix(ar, br), mix(ag, bg), mix(ab, bb), mix(aa, ba)}
+}
+
+// mod returns the non negative remainder of a / b.
+func mod(a, b int) int {
+	return (a%b + b) % b
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package pattern
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestFill(t *testing.T) {
+	marker := color.RGBA{0xff, 0, 0, 0xff}
+
+	// A 4x4 gray motif with a marker in its top left pixel.
+	motif := image.NewRGBA(image.Rect(10, 10, 14, 14))
+
+	for y := 10; y < 14; y++ {
+		for x := 10; x < 14; x++ {
+			motif.Set(x, y, color.Gray{0x80})
+		}
+	}
+
+	motif.Set(10, 10, marker)
+
+	cases := []struct {
+		repeat string
+		r      image.Rectangle
+		want   []image.Point
+	}{
+		{
+			Straight,
+			image.Rect(0, 0, 8, 8),
+			[]image.Point{{0, 0}, {4, 0}, {0, 4}, {4, 4}},
+		},
+		{
+			Straight,
+			image.Rect(1, 1, 9, 9),
+			[]image.Point{{1, 1}, {5, 1}, {1, 5}, {5, 5}},
+		},
+		{
+			HalfDrop,
+			image.Rect(0, 0, 8, 8),
+			[]image.Point{{0, 0}, {4, 2}, {0, 4}, {4, 6}},
+		},
+		{
+			Brick,
+			image.Rect(0, 0, 8, 8),
+			[]image.Point{{0, 0}, {4, 0}, {2, 4}, {6, 4}},
+		},
+		{
+			Mirror,
+			image.Rect(0, 0, 8, 8),
+			[]image.Point{{0, 0}, {7, 0}, {0, 7}, {7, 7}},
+		},
+	}
+
+	for _, c := range cases {
+		dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+		if err := Fill(dst, c.r, []image.Image{motif}, c.repeat); err != nil {
+			t.Errorf("%s %v: unexpected error %v", c.repeat, c.r, err)
+			continue
+		}
+
+		var got []image.Point
+
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				if dst.RGBAAt(x, y) == marker {
+					got = append(got, image.Pt(x, y))
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %v: got markers at %v, want %v", c.repeat, c.r, got, c.want)
+		}
+
+		// The pattern covers r and nothing else.
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				in := image.Pt(x, y).In(c.r)
+
+				if covered := dst.RGBAAt(x, y).A != 0; covered != in {
+					t.Errorf("%s %v: pixel %d,%d covered = %v", c.repeat, c.r, x, y, covered)
+				}
+			}
+		}
+	}
+}
+
+func TestFillErrors(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	motif := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	cases := []struct {
+		motifs []image.Image
+		repeat string
+	}{
+		{nil, Straight},
+		{[]image.Image{motif}, "diagonal"},
+		{[]image.Image{image.NewRGBA(image.Rectangle{})}, Straight},
+	}
+
+	for _, c := range cases {
+		if err := Fill(dst, dst.Bounds(), c.motifs, c.repeat); err == nil {
+			t.Errorf("%d motifs, %s: expected an error", len(c.motifs), c.repeat)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+// Package pyramid provides zoomable tile pyramids generation in Deep Zoom
+// (DZI) and XYZ layouts.
+package pyramid
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// Supported layouts.
+const (
+	DZI = "dzi"
+	XYZ = "xyz"
+)
+
+// DefaultOptions is a set of commonly used options and may be used as a
+// starter point for custom options.
+var DefaultOptions = &Options{
+	Layout:   DZI,
+	TileSize: 256,
+	Overlap:  1,
+}
+
+// Options are the pyramid generation options.
+type Options struct {
+	// Layout is the pyramid layout, DZI or XYZ.
+	Layout string
+
+	// TileSize is the width and height of the tiles.
+	TileSize int
+
+	// Overlap is the number of pixels shared by neighbor tiles, only used by
+	// the DZI layout.
+	Overlap int
+
+	// Background is the color used for padding XYZ tiles at the image edges,
+	// if nil, they are transparent.
+	Background color.Color
+}
+
+// Info describes a generated pyramid.
+type Info struct {
+	Width, Height int
+	MaxLevel      int
+}
+
+// TileFunc receives every tile of a pyramid, with its level, column and row.
+// For the XYZ layout, level is the zoom, column is x and row is y.
+type TileFunc func(level, col, row int, tile image.Image) error
+
+// Generate cuts img into a tile pyramid and calls fn for every tile, from the
+// highest resolution level to the lowest. Only one level is kept in memory
+// besides img.
+func Generate(img image.Image, o *Options, fn TileFunc) (Info, error) {
+	if o == nil {
+		o = DefaultOptions
+	}
+
+	if o.TileSize < 1 {
+		return Info{}, fmt.Errorf("pyramid: invalid tile size %d", o.TileSize)
+	}
+
+	b := img.Bounds()
+	info := Info{Width: b.Dx(), Height: b.Dy()}
+
+	switch o.Layout {
+	case DZI:
+		info.MaxLevel = levels(max(b.Dx(), b.Dy()), 1)
+	case XYZ:
+		info.MaxLevel = levels(max(b.Dx(), b.Dy()), o.TileSize)
+	default:
+		return Info{}, fmt.Errorf("pyramid: unknown layout '%s'", o.Layout)
+	}
+
+	level := img
+
+	for l := info.MaxLevel; l >= 0; l-- {
+		if l < info.MaxLevel {
+			level = half(level)
+		}
+
+		var err error
+
+		if o.Layout == DZI {
+			err = cutDZI(level, l, o, fn)
+		} else {
+			err = cutXYZ(level, l, o, fn)
+		}
+
+		if err != nil {
+			return info, err
+		}
+	}
+
+	return info, nil
+}
+
+// levels returns the number of times n must be halved to be at most min.
+func levels(n, min int) int {
+	l := 0
+
+	for n > min {
+		n = (n + 1) / 2
+		l++
+	}
+
+	return l
+}
+
+// half returns img scaled to half its size, rounding up.
+func half(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, (b.Dx()+1)/2, (b.Dy()+1)/2))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// cutDZI cuts a level in DZI tiles, edge tiles are not padded.
+func cutDZI(img image.Image, level int, o *Options, fn TileFunc) error {
+	b := img.Bounds()
+	ts, ov := o.TileSize, o.Overlap
+
+	for row := 0; row*ts < b.Dy(); row++ {
+		for col := 0; col*ts < b.Dx(); col++ {
+			r := image.Rect(col*ts-ov, row*ts-ov, (col+1)*ts+ov, (row+1)*ts+ov)
+			r = r.Add(b.Min).Intersect(b)
+
+			if err := fn(level, col, row, subImage(img, r)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cutXYZ cuts a level in XYZ tiles, edge tiles are padded to the tile size.
+func cutXYZ(img image.Image, zoom int, o *Options, fn TileFunc) error {
+	b := img.Bounds()
+	ts := o.TileSize
+
+	for y := 0; y*ts < b.Dy(); y++ {
+		for x := 0; x*ts < b.Dx(); x++ {
+			r := image.Rect(x*ts, y*ts, (x+1)*ts, (y+1)*ts).Add(b.Min)
+			t := subImage(img, r.Intersect(b))
+
+			if t.Bounds().Dx() < ts || t.Bounds().Dy() < ts {
+				dst := image.NewRGBA(image.Rect(0, 0, ts, ts))
+
+				if o.Background != nil {
+					draw.Draw(dst, dst.Bounds(), &image.Uniform{o.Background}, image.ZP, draw.Src)
+				}
+
+				draw.Draw(dst, dst.Bounds(), t, r.Min, draw.Over)
+				t = dst
+			}
+
+			if err := fn(zoom, x, y, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteDZI writes the DZI descriptor of a pyramid with tiles in the given
+// image format (e.g. "png").
+func WriteDZI(w io.Writer, info Info, format string, o *Options) error {
+	type size struct {
+		Width  int `xml:"Width,attr"`
+		Height int `xml:"Height,attr"`
+	}
+
+	desc := struct {
+		XMLName  xml.Name `xml:"http://schemas.microsoft.com/deepzoom/2008 Image"`
+		Format   string   `xml:"Format,attr"`
+		Overlap  int      `xml:"Overlap,attr"`
+		TileSize int      `xml:"TileSize,attr"`
+		Size     size     `xml:"Size"`
+	}{
+		Format:   format,
+		Overlap:  o.Overlap,
+		TileSize: o.TileSize,
+		Size:     size{info.Width, info.Height},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(desc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteXYZ writes a JSON descriptor of a XYZ pyramid, tiles is the URL
+// template of the tiles (e.g. "{z}/{x}/{y}.png").
+func WriteXYZ(w io.Writer, info Info, tiles string, o *Options) error {
+	desc := struct {
+		Tiles    []string `json:"tiles"`
+		MinZoom  int      `json:"minzoom"`
+		MaxZoom  int      `json:"maxzoom"`
+		TileSize int      `json:"tileSize"`
+		Width    int      `json:"width"`
+		Height   int      `json:"height"`
+	}{
+		Tiles:    []string{tiles},
+		MaxZoom:  info.MaxLevel,
+		TileSize: o.TileSize,
+		Width:    info.Width,
+		Height:   info.Height,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(desc)
+}
+
+// subImage returns the r area of img.
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if si, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return si.SubImage(r)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package pyramid
+
+import (
+	"image"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	cases := []struct {
+		layout   string
+		size     image.Point
+		tileSize int
+		maxLevel int
+		tiles    []int
+	}{
+		// 600x400, 300x200, 150x100, ... 1x1.
+		{DZI, image.Pt(600, 400), 256, 10, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 6}},
+		{DZI, image.Pt(256, 256), 256, 8, []int{1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{DZI, image.Pt(1, 1), 256, 0, []int{1}},
+		{XYZ, image.Pt(600, 400), 256, 2, []int{1, 2, 6}},
+		{XYZ, image.Pt(256, 100), 256, 0, []int{1}},
+		{XYZ, image.Pt(257, 257), 256, 1, []int{1, 4}},
+	}
+
+	for _, c := range cases {
+		o := &Options{Layout: c.layout, TileSize: c.tileSize, Overlap: 1}
+		img := image.NewRGBA(image.Rectangle{Max: c.size})
+		tiles := make([]int, c.maxLevel+1)
+		last := c.maxLevel
+
+		info, err := Generate(img, o, func(level, col, row int, tile image.Image) error {
+			if level < 0 || level > c.maxLevel {
+				t.Errorf("%s %v: unexpected level %d", c.layout, c.size, level)
+				return nil
+			}
+
+			if level > last {
+				t.Errorf("%s %v: level %d after level %d", c.layout, c.size, level, last)
+			}
+
+			last = level
+			tiles[level]++
+			s := tile.Bounds().Size()
+
+			if c.layout == XYZ && s != image.Pt(c.tileSize, c.tileSize) {
+				t.Errorf("%s %v: tile %d/%d/%d is %v", c.layout, c.size, level, col, row, s)
+			}
+
+			if max := c.tileSize + 2*o.Overlap; s.X > max || s.Y > max {
+				t.Errorf("%s %v: tile %d/%d/%d is %v", c.layout, c.size, level, col, row, s)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			t.Errorf("%s %v: unexpected error %v", c.layout, c.size, err)
+			continue
+		}
+
+		if info.MaxLevel != c.maxLevel {
+			t.Errorf("%s %v: got max level %d, want %d", c.layout, c.size, info.MaxLevel, c.maxLevel)
+		}
+
+		for l, n := range c.tiles {
+			if tiles[l] != n {
+				t.Errorf("%s %v: got %d tiles in level %d, want %d", c.layout, c.size, tiles[l], l, n)
+			}
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	cases := []*Options{
+		{Layout: DZI, TileSize: 0},
+		{Layout: "tms", TileSize: 256},
+	}
+
+	for _, o := range cases {
+		_, err := Generate(img, o, func(level, col, row int, tile image.Image) error {
+			return nil
+		})
+
+		if err == nil {
+			t.Errorf("%+v: expected an error", *o)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	cases := []struct {
+		n, min, want int
+	}{
+		{1, 1, 0},
+		{2, 1, 1},
+		{3, 1, 2},
+		{600, 1, 10},
+		{256, 256, 0},
+		{257, 256, 1},
+		{1024, 256, 2},
+	}
+
+	for _, c := range cases {
+		if got := levels(c.n, c.min); got != c.want {
+			t.Errorf("levels(%d, %d) = %d, want %d", c.n, c.min, got, c.want)
+		}
+	}
+}
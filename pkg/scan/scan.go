@@ -0,0 +1,357 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+// Package scan provides detection of photos in flatbed scans.
+package scan
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// workSize is the maximum size of the longest side of the image used for
+// detection, bigger images are sampled.
+const workSize = 1000
+
+// ErrEmpty is returned when the scan has no pixels.
+var ErrEmpty = errors.New("scan: the image is empty")
+
+// DefaultOptions is a set of commonly used detection options and may be used
+// as a starter point for custom options.
+var DefaultOptions = &Options{
+	Threshold: 0.1,
+	MinArea:   0.01,
+	MaxAngle:  15,
+}
+
+// Options are the detection options.
+type Options struct {
+	// Background is the color of the scanner background, if nil, it is
+	// estimated from the image borders.
+	Background color.Color
+
+	// Threshold is the minimum distance (0-1) from the background color for a
+	// pixel to be part of a photo.
+	Threshold float64
+
+	// MinArea is the minimum area of a photo, relative to the image area.
+	MinArea float64
+
+	// MaxAngle is the maximum rotation, in degrees, that is corrected.
+	MaxAngle float64
+}
+
+// Region is a photo found in a scan.
+type Region struct {
+	// Bounds is the axis aligned area that contains the photo.
+	Bounds image.Rectangle
+
+	// Center is the center of the photo.
+	Center f64.Vec2
+
+	// Width and Height are the size of the photo once its rotation is
+	// corrected.
+	Width, Height int
+
+	// Angle is the rotation of the photo, in degrees.
+	Angle float64
+}
+
+// Detect returns the regions of img that contain photos, from top to bottom
+// and left to right.
+func Detect(img image.Image, o *Options) ([]Region, error) {
+	if o == nil {
+		o = DefaultOptions
+	}
+
+	b := img.Bounds()
+
+	if b.Empty() {
+		return nil, ErrEmpty
+	}
+
+	step := (max(b.Dx(), b.Dy()) + workSize - 1) / workSize
+	w, h := (b.Dx()+step-1)/step, (b.Dy()+step-1)/step
+
+	bg := o.Background
+
+	if bg == nil {
+		bg = borderColor(img, step)
+	}
+
+	mask := make([]bool, w*h)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x*step, b.Min.Y+y*step)
+			mask[y*w+x] = distance(c, bg) > o.Threshold
+		}
+	}
+
+	// Closing fills small holes left by photo areas similar to the
+	// background.
+	mask = dilate(mask, w, h)
+	mask = erode(mask, w, h)
+
+	var regions []Region
+	minArea := int(o.MinArea * float64(w*h))
+	seen := make([]bool, w*h)
+
+	for i := range mask {
+		if !mask[i] || seen[i] {
+			continue
+		}
+
+		border, area := component(mask, seen, w, h, i)
+
+		if area < minArea || area == 0 {
+			continue
+		}
+
+		// Work coordinates to image coordinates, using pixel centers.
+		for j := range border {
+			border[j][0] = float64(b.Min.X) + (border[j][0]+0.5)*float64(step)
+			border[j][1] = float64(b.Min.Y) + (border[j][1]+0.5)*float64(step)
+		}
+
+		regions = append(regions, fit(border, o.MaxAngle, float64(step)))
+	}
+
+	sortRegions(regions)
+	return regions, nil
+}
+
+// Extract returns the photo in the r region of img, with its rotation
+// corrected.
+func Extract(img image.Image, r Region) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	sin, cos := math.Sincos(r.Angle * math.Pi / 180)
+	c := rotate(r.Center, sin, cos)
+
+	s2d := f64.Aff3{
+		cos, sin, float64(r.Width)/2 - c[0],
+		-sin, cos, float64(r.Height)/2 - c[1],
+	}
+
+	draw.BiLinear.Transform(dst, s2d, img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// fit returns the region with the smallest area that contains the border
+// points, trying rotations up to maxAngle degrees.
+func fit(border []f64.Vec2, maxAngle, step float64) Region {
+	var (
+		best     Region
+		bestArea = math.Inf(1)
+	)
+
+	for a := -maxAngle; a <= maxAngle+1e-9; a += 0.25 {
+		sin, cos := math.Sincos(a * math.Pi / 180)
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+		for _, p := range border {
+			q := rotate(p, sin, cos)
+			minX, maxX = math.Min(minX, q[0]), math.Max(maxX, q[0])
+			minY, maxY = math.Min(minY, q[1]), math.Max(maxY, q[1])
+		}
+
+		// Border points are pixel centers, so half a pixel is added on every
+		// side.
+		minX, minY = minX-step/2, minY-step/2
+		maxX, maxY = maxX+step/2, maxY+step/2
+
+		// Ties are resolved in favor of the smallest rotation.
+		area := (maxX - minX) * (maxY - minY)
+
+		if area < bestArea-1e-6 || area <= bestArea+1e-6 && math.Abs(a) < math.Abs(best.Angle) {
+			bestArea = area
+			c := f64.Vec2{(minX + maxX) / 2, (minY + maxY) / 2}
+
+			best = Region{
+				Center: f64.Vec2{cos*c[0] - sin*c[1], sin*c[0] + cos*c[1]},
+				Width:  int(maxX - minX + 0.5),
+				Height: int(maxY - minY + 0.5),
+				Angle:  a,
+			}
+		}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, p := range border {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+
+	best.Bounds = image.Rect(
+		int(minX-step/2),
+		int(minY-step/2),
+		int(math.Ceil(maxX+step/2)),
+		int(math.Ceil(maxY+step/2)),
+	)
+
+	return best
+}
+
+// rotate rotates p by the angle with the given sine and cosine, in the
+// opposite direction of the image rotation.
+func rotate(p f64.Vec2, sin, cos float64) f64.Vec2 {
+	return f64.Vec2{cos*p[0] + sin*p[1], -sin*p[0] + cos*p[1]}
+}
+
+// component returns the border points and the area of the connected component
+// of mask that contains i, marking its points as seen.
+func component(mask, seen []bool, w, h, i int) ([]f64.Vec2, int) {
+	var border []f64.Vec2
+	area := 0
+	stack := []int{i}
+	seen[i] = true
+
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		area++
+
+		x, y := p%w, p/w
+		isBorder := false
+
+		for _, d := range [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			nx, ny := x+d[0], y+d[1]
+
+			if nx < 0 || ny < 0 || nx >= w || ny >= h {
+				isBorder = true
+				continue
+			}
+
+			n := ny*w + nx
+
+			if !mask[n] {
+				isBorder = true
+				continue
+			}
+
+			if !seen[n] {
+				seen[n] = true
+				stack = append(stack, n)
+			}
+		}
+
+		if isBorder {
+			border = append(border, f64.Vec2{float64(x), float64(y)})
+		}
+	}
+
+	return border, area
+}
+
+// dilate returns mask with every set point expanded to its neighbors.
+func dilate(mask []bool, w, h int) []bool {
+	return morph(mask, w, h, true)
+}
+
+// erode returns mask with every unset point expanded to its neighbors.
+func erode(mask []bool, w, h int) []bool {
+	return morph(mask, w, h, false)
+}
+
+func morph(mask []bool, w, h int, v bool) []bool {
+	out := make([]bool, len(mask))
+	copy(out, mask)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if mask[y*w+x] != v {
+				continue
+			}
+
+			for ny := y - 1; ny <= y+1; ny++ {
+				for nx := x - 1; nx <= x+1; nx++ {
+					if nx >= 0 && ny >= 0 && nx < w && ny < h {
+						out[ny*w+nx] = v
+					}
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// borderColor returns the average color of the img borders, sampled every
+// step pixels.
+func borderColor(img image.Image, step int) color.Color {
+	b := img.Bounds()
+	var r, g, bl, n uint64
+
+	add := func(x, y int) {
+		cr, cg, cb, _ := img.At(x, y).RGBA()
+		r, g, bl, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), n+1
+	}
+
+	for x := b.Min.X; x < b.Max.X; x += step {
+		add(x, b.Min.Y)
+		add(x, b.Max.Y-1)
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		add(b.Min.X, y)
+		add(b.Max.X-1, y)
+	}
+
+	if n == 0 {
+		return color.White
+	}
+
+	return color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), 0xFFFF}
+}
+
+// distance returns the normalized (0-1) distance between a and b.
+func distance(a, b color.Color) float64 {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	dr := float64(ar) - float64(br)
+	dg := float64(ag) - float64(bg)
+	db := float64(ab) - float64(bb)
+	return math.Sqrt(dr*dr+dg*dg+db*db) / (math.Sqrt(3) * 0xFFFF)
+}
+
+// sortRegions sorts regions from top to bottom and left to right, regions
+// whose centers are vertically close are considered in the same row.
+func sortRegions(regions []Region) {
+	less := func(a, b Region) bool {
+		if math.Abs(a.Center[1]-b.Center[1]) > float64(min(a.Height, b.Height))/2 {
+			return a.Center[1] < b.Center[1]
+		}
+
+		return a.Center[0] < b.Center[0]
+	}
+
+	for i := 1; i < len(regions); i++ {
+		for j := i; j > 0 && less(regions[j], regions[j-1]); j-- {
+			regions[j], regions[j-1] = regions[j-1], regions[j]
+		}
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
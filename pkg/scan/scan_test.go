@@ -0,0 +1,134 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package scan
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// photo is a rectangle of a synthetic scan.
+type photo struct {
+	center        image.Point
+	width, height int
+	angle         float64
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name   string
+		photos []photo
+	}{
+		{"empty", nil},
+		{"single", []photo{{image.Pt(200, 150), 200, 100, 0}}},
+		{
+			"grid",
+			[]photo{
+				{image.Pt(100, 80), 120, 90, 0},
+				{image.Pt(300, 80), 100, 100, 0},
+				{image.Pt(100, 220), 150, 80, 0},
+				{image.Pt(300, 220), 80, 100, 0},
+			},
+		},
+		{
+			"rotated",
+			[]photo{
+				{image.Pt(110, 150), 120, 80, 10},
+				{image.Pt(290, 150), 100, 140, -5},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		regions, err := Detect(sheet(image.Rect(0, 0, 400, 300), c.photos), nil)
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+
+		if len(regions) != len(c.photos) {
+			t.Errorf("%s: got %d regions, want %d", c.name, len(regions), len(c.photos))
+			continue
+		}
+
+		for i, r := range regions {
+			p := c.photos[i]
+
+			if math.Abs(r.Center[0]-float64(p.center.X)) > 2 || math.Abs(r.Center[1]-float64(p.center.Y)) > 2 {
+				t.Errorf("%s: region %d centered at %v, want %v", c.name, i, r.Center, p.center)
+			}
+
+			if abs(r.Width-p.width) > 2 || abs(r.Height-p.height) > 2 {
+				t.Errorf("%s: region %d is %dx%d, want %dx%d", c.name, i, r.Width, r.Height, p.width, p.height)
+			}
+
+			if math.Abs(r.Angle-p.angle) > 0.5 {
+				t.Errorf("%s: region %d rotated %v, want %v", c.name, i, r.Angle, p.angle)
+			}
+
+			if !p.bounds().In(r.Bounds.Inset(-1)) {
+				t.Errorf("%s: region %d bounds %v don't contain %v", c.name, i, r.Bounds, p.bounds())
+			}
+		}
+	}
+}
+
+func TestDetectEmpty(t *testing.T) {
+	if _, err := Detect(image.NewRGBA(image.Rectangle{}), nil); err != ErrEmpty {
+		t.Errorf("got error %v, want %v", err, ErrEmpty)
+	}
+}
+
+// sheet returns a white scan of size r with dark photos.
+func sheet(r image.Rectangle, photos []photo) image.Image {
+	img := image.NewRGBA(r)
+	draw.Draw(img, r, image.White, image.ZP, draw.Src)
+
+	for _, p := range photos {
+		sin, cos := math.Sincos(p.angle * math.Pi / 180)
+
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				// Pixel center in the photo coordinates.
+				dx := float64(x) + 0.5 - float64(p.center.X)
+				dy := float64(y) + 0.5 - float64(p.center.Y)
+				u, v := cos*dx+sin*dy, -sin*dx+cos*dy
+
+				if math.Abs(u) < float64(p.width)/2 && math.Abs(v) < float64(p.height)/2 {
+					img.Set(x, y, color.Gray{0x40})
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// bounds returns the axis aligned area that contains p.
+func (p photo) bounds() image.Rectangle {
+	sin, cos := math.Sincos(p.angle * math.Pi / 180)
+	w, h := float64(p.width)/2, float64(p.height)/2
+	dx := math.Abs(w*cos) + math.Abs(h*sin)
+	dy := math.Abs(w*sin) + math.Abs(h*cos)
+
+	return image.Rect(
+		int(math.Ceil(float64(p.center.X)-dx)),
+		int(math.Ceil(float64(p.center.Y)-dy)),
+		int(math.Floor(float64(p.center.X)+dx)),
+		int(math.Floor(float64(p.center.Y)+dy)),
+	)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
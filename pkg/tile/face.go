@@ -0,0 +1,219 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package tile
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// face is a font.Face of a sfnt.Font, glyphs are rasterized with
+// vector.Rasterizer.
+type face struct {
+	f    *sfnt.Font
+	ppem fixed.Int26_6
+
+	buf  sfnt.Buffer
+	rast vector.Rasterizer
+}
+
+// Close implements font.Face.
+func (a *face) Close() error {
+	return nil
+}
+
+// Metrics implements font.Face.
+func (a *face) Metrics() font.Metrics {
+	m, err := a.f.Metrics(&a.buf, a.ppem, font.HintingFull)
+
+	if err != nil {
+		return font.Metrics{}
+	}
+
+	return m
+}
+
+// Kern implements font.Face.
+func (a *face) Kern(r0, r1 rune) fixed.Int26_6 {
+	x0, err0 := a.f.GlyphIndex(&a.buf, r0)
+	x1, err1 := a.f.GlyphIndex(&a.buf, r1)
+
+	if err0 != nil || err1 != nil {
+		return 0
+	}
+
+	k, err := a.f.Kern(&a.buf, x0, x1, a.ppem, font.HintingFull)
+
+	if err != nil {
+		return 0
+	}
+
+	return k
+}
+
+// GlyphAdvance implements font.Face.
+func (a *face) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	x, err := a.f.GlyphIndex(&a.buf, r)
+
+	if err != nil {
+		return 0, false
+	}
+
+	adv, err := a.f.GlyphAdvance(&a.buf, x, a.ppem, font.HintingFull)
+	return adv, err == nil
+}
+
+// GlyphBounds implements font.Face. Bounds include the control points of
+// the glyph curves, so they may be slightly bigger than the glyph.
+func (a *face) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	segs, adv, ok := a.load(r)
+
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+
+	return segmentBounds(segs), adv, true
+}
+
+// Glyph implements font.Face. The returned mask is only valid until the next
+// call to Glyph.
+func (a *face) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	segs, adv, ok := a.load(r)
+
+	if !ok {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+
+	b := segmentBounds(segs)
+
+	dr := image.Rect(
+		(dot.X + b.Min.X).Floor(),
+		(dot.Y + b.Min.Y).Floor(),
+		(dot.X + b.Max.X).Ceil(),
+		(dot.Y + b.Max.Y).Ceil(),
+	)
+
+	mask := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+
+	if dr.Empty() {
+		return dr, mask, image.Point{}, adv, true
+	}
+
+	// Segments are relative to the dot, the rasterizer origin is the top
+	// left corner of dr.
+	bx := float32(dot.X-fixed.I(dr.Min.X)) / 64
+	by := float32(dot.Y-fixed.I(dr.Min.Y)) / 64
+	pt := func(p fixed.Point26_6) (float32, float32) {
+		return float32(p.X)/64 + bx, float32(p.Y)/64 + by
+	}
+
+	a.rast.Reset(dr.Dx(), dr.Dy())
+	a.rast.DrawOp = draw.Src
+	open := false
+
+	for _, s := range segs {
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			if open {
+				a.rast.ClosePath()
+			}
+
+			a.rast.MoveTo(pt(s.Args[0]))
+			open = true
+		case sfnt.SegmentOpLineTo:
+			a.rast.LineTo(pt(s.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			x0, y0 := pt(s.Args[0])
+			x1, y1 := pt(s.Args[1])
+			a.rast.QuadTo(x0, y0, x1, y1)
+		case sfnt.SegmentOpCubeTo:
+			x0, y0 := pt(s.Args[0])
+			x1, y1 := pt(s.Args[1])
+			x2, y2 := pt(s.Args[2])
+			a.rast.CubeTo(x0, y0, x1, y1, x2, y2)
+		}
+	}
+
+	if open {
+		a.rast.ClosePath()
+	}
+
+	a.rast.Draw(mask, mask.Bounds(), image.Opaque, image.ZP)
+	return dr, mask, image.Point{}, adv, true
+}
+
+// load returns the segments and the advance of the glyph of r. Glyphs
+// missing in the font are replaced by the font's missing glyph.
+func (a *face) load(r rune) ([]sfnt.Segment, fixed.Int26_6, bool) {
+	x, err := a.f.GlyphIndex(&a.buf, r)
+
+	if err != nil {
+		return nil, 0, false
+	}
+
+	// The advance is read first, since the segments are only valid until
+	// the buffer is used again.
+	adv, err := a.f.GlyphAdvance(&a.buf, x, a.ppem, font.HintingFull)
+
+	if err != nil {
+		return nil, 0, false
+	}
+
+	segs, err := a.f.LoadGlyph(&a.buf, x, a.ppem, nil)
+
+	if err != nil {
+		return nil, 0, false
+	}
+
+	return segs, adv, true
+}
+
+// segmentBounds returns the bounds of the points of segs.
+func segmentBounds(segs []sfnt.Segment) fixed.Rectangle26_6 {
+	var (
+		b     fixed.Rectangle26_6
+		first = true
+	)
+
+	for _, s := range segs {
+		n := 1
+
+		switch s.Op {
+		case sfnt.SegmentOpQuadTo:
+			n = 2
+		case sfnt.SegmentOpCubeTo:
+			n = 3
+		}
+
+		for _, p := range s.Args[:n] {
+			if first {
+				b.Min, b.Max, first = p, p, false
+				continue
+			}
+
+			if p.X < b.Min.X {
+				b.Min.X = p.X
+			}
+
+			if p.Y < b.Min.Y {
+				b.Min.Y = p.Y
+			}
+
+			if p.X > b.Max.X {
+				b.Max.X = p.X
+			}
+
+			if p.Y > b.Max.Y {
+				b.Max.Y = p.Y
+			}
+		}
+	}
+
+	return b
+}
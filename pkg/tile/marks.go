@@ -0,0 +1,117 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package tile
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// DrawLine draws a horizontal or vertical line from a to b with w width and c
+// color. If dash is greater than 0, the line is dashed with segments of dash
+// length. Only horizontal and vertical lines are supported, any other line is
+// drawn along the longest side of its bounding box.
+func DrawLine(dst draw.Image, a, b image.Point, w int, c color.Color, dash int) {
+	if w < 1 {
+		w = 1
+	}
+
+	src := &image.Uniform{c}
+	r := image.Rectangle{a, b}.Canon()
+	horizontal := r.Dx() >= r.Dy()
+
+	// Lines are centered in their position.
+	if horizontal {
+		r.Min.Y -= w / 2
+		r.Max.Y = r.Min.Y + w
+	} else {
+		r.Min.X -= w / 2
+		r.Max.X = r.Min.X + w
+	}
+
+	if dash <= 0 {
+		draw.Draw(dst, r, src, image.ZP, draw.Over)
+		return
+	}
+
+	for i := 0; ; i += 2 * dash {
+		seg := r
+
+		if horizontal {
+			seg.Min.X = r.Min.X + i
+			seg.Max.X = seg.Min.X + dash
+		} else {
+			seg.Min.Y = r.Min.Y + i
+			seg.Max.Y = seg.Min.Y + dash
+		}
+
+		seg = seg.Intersect(r)
+
+		if seg.Empty() {
+			return
+		}
+
+		draw.Draw(dst, seg, src, image.ZP, draw.Over)
+	}
+}
+
+// DrawCross draws a cross centered at p, with arms of size length, w width
+// and c color.
+func DrawCross(dst draw.Image, p image.Point, size, w int, c color.Color) {
+	DrawLine(dst, p.Sub(image.Pt(size, 0)), p.Add(image.Pt(size+1, 0)), w, c, 0)
+	DrawLine(dst, p.Sub(image.Pt(0, size)), p.Add(image.Pt(0, size+1)), w, c, 0)
+}
+
+// CropMarks returns the crop marks of trim as segments, two at every corner,
+// of size length and placed off pixels away from the corner, outside trim.
+func CropMarks(trim image.Rectangle, size, off int) [][2]image.Point {
+	var marks [][2]image.Point
+
+	for _, c := range []image.Point{
+		trim.Min,
+		{trim.Max.X, trim.Min.Y},
+		trim.Max,
+		{trim.Min.X, trim.Max.Y},
+	} {
+		// Outward directions from the corner.
+		dx, dy := -1, -1
+
+		if c.X == trim.Max.X {
+			dx = 1
+		}
+
+		if c.Y == trim.Max.Y {
+			dy = 1
+		}
+
+		marks = append(
+			marks,
+			[2]image.Point{{c.X + dx*off, c.Y}, {c.X + dx*(off+size), c.Y}},
+			[2]image.Point{{c.X, c.Y + dy*off}, {c.X, c.Y + dy*(off+size)}},
+		)
+	}
+
+	return marks
+}
+
+// DrawRegistration draws a registration mark centered at p, a cross with arms
+// of size length and a circle, with w width and c color.
+func DrawRegistration(dst draw.Image, p image.Point, size, w int, c color.Color) {
+	DrawCross(dst, p, size, w, c)
+
+	const steps = 48
+	circle := make(Polygon, steps)
+	r := float64(size) * 0.6
+
+	for i := range circle {
+		a := 2 * math.Pi * float64(i) / steps
+		circle[i] = f64.Vec2{float64(p.X) + 0.5 + r*math.Cos(a), float64(p.Y) + 0.5 + r*math.Sin(a)}
+	}
+
+	DrawOutline(dst, circle, w, c)
+}
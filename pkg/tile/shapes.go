@@ -0,0 +1,287 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package tile
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Polygon is a convex polygon. It is also an anti-aliased alpha mask (see
+// image/draw.DrawMask) that is opaque inside the polygon.
+type Polygon []f64.Vec2
+
+// ColorModel implements image.Image.
+func (p Polygon) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+// Bounds implements image.Image.
+func (p Polygon) Bounds() image.Rectangle {
+	if len(p) == 0 {
+		return image.Rectangle{}
+	}
+
+	min, max := p[0], p[0]
+
+	for _, v := range p[1:] {
+		min[0], min[1] = math.Min(min[0], v[0]), math.Min(min[1], v[1])
+		max[0], max[1] = math.Max(max[0], v[0]), math.Max(max[1], v[1])
+	}
+
+	return image.Rect(
+		int(math.Floor(min[0])),
+		int(math.Floor(min[1])),
+		int(math.Ceil(max[0])),
+		int(math.Ceil(max[1])),
+	)
+}
+
+// At implements image.Image.
+func (p Polygon) At(x, y int) color.Color {
+	return color.Alpha{coverage(p.distance(float64(x)+0.5, float64(y)+0.5) + 0.5)}
+}
+
+// distance returns the signed distance from (x, y) to the nearest side of p,
+// positive inside p.
+func (p Polygon) distance(x, y float64) float64 {
+	if len(p) < 3 {
+		return math.Inf(-1)
+	}
+
+	// Sides are oriented according to the polygon winding.
+	area := 0.0
+
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+
+	sign := 1.0
+
+	if area < 0 {
+		sign = -1
+	}
+
+	d := math.Inf(1)
+
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		l := math.Hypot(dx, dy)
+
+		if l == 0 {
+			continue
+		}
+
+		d = math.Min(d, sign*(dx*(y-a[1])-dy*(x-a[0]))/l)
+	}
+
+	return d
+}
+
+// RectPolygon returns the polygon of r.
+func RectPolygon(r image.Rectangle) Polygon {
+	x0, y0 := float64(r.Min.X), float64(r.Min.Y)
+	x1, y1 := float64(r.Max.X), float64(r.Max.Y)
+	return Polygon{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+}
+
+// RoundedRect returns the polygon of r with corners rounded by radius.
+func RoundedRect(r image.Rectangle, radius float64) Polygon {
+	radius = math.Min(radius, math.Min(float64(r.Dx()), float64(r.Dy()))/2)
+
+	if radius < 1 {
+		return RectPolygon(r)
+	}
+
+	// Corner centers and the angle where their arcs start, clockwise from
+	// the top right corner.
+	x0, y0 := float64(r.Min.X)+radius, float64(r.Min.Y)+radius
+	x1, y1 := float64(r.Max.X)-radius, float64(r.Max.Y)-radius
+	corners := [4][3]float64{{x1, y0, -90}, {x1, y1, 0}, {x0, y1, 90}, {x0, y0, 180}}
+
+	const steps = 8
+	p := make(Polygon, 0, 4*(steps+1))
+
+	for _, c := range corners {
+		for i := 0; i <= steps; i++ {
+			a := math.Pi / 180 * (c[2] + 90*float64(i)/steps)
+			p = append(p, f64.Vec2{c[0] + radius*math.Cos(a), c[1] + radius*math.Sin(a)})
+		}
+	}
+
+	return p
+}
+
+// outline is an anti-aliased alpha mask of the sides of a polygon.
+type outline struct {
+	p Polygon
+	w float64
+}
+
+func (o outline) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+func (o outline) Bounds() image.Rectangle {
+	w := int(math.Ceil(o.w))
+	return o.p.Bounds().Inset(-w)
+}
+
+func (o outline) At(x, y int) color.Color {
+	px, py := float64(x)+0.5, float64(y)+0.5
+	d := math.Inf(1)
+
+	for i, a := range o.p {
+		b := o.p[(i+1)%len(o.p)]
+		d = math.Min(d, segmentDistance(px, py, a, b))
+	}
+
+	return color.Alpha{coverage(o.w/2 + 0.5 - d)}
+}
+
+// DrawOutline draws the sides of p with w width and c color.
+func DrawOutline(dst draw.Image, p Polygon, w int, c color.Color) {
+	if w < 1 || len(p) < 2 {
+		return
+	}
+
+	o := outline{p, float64(w)}
+	r := o.Bounds()
+	draw.DrawMask(dst, r, &image.Uniform{c}, image.ZP, o, r.Min, draw.Over)
+}
+
+// HexGrid returns at least t pointy top hexagonal tiles that fit in r, along
+// with their shapes. Odd rows are shifted by half a tile and tiles are
+// ordered like Grid.
+func HexGrid(r image.Rectangle, t int64) ([]image.Rectangle, []Polygon) {
+	if t < 1 {
+		return nil, nil
+	}
+
+	W, H := float64(r.Dx()), float64(r.Dy())
+	var cols, rows int
+	s := 0.0
+
+	for c := 1; c <= int(t); c++ {
+		l := (int(t) + c - 1) / c
+		w := float64(c)
+
+		if l > 1 {
+			w += 0.5
+		}
+
+		if size := math.Min(W/(math.Sqrt(3)*w), H/(1.5*float64(l)+0.5)); size > s {
+			s, cols, rows = size, c, l
+		}
+	}
+
+	w := math.Sqrt(3) * s
+	gw, gh := w*float64(cols), 1.5*s*float64(rows)+0.5*s
+
+	if rows > 1 {
+		gw += w / 2
+	}
+
+	x0 := float64(r.Min.X) + (W-gw)/2
+	y0 := float64(r.Min.Y) + (H-gh)/2
+	var shapes []Polygon
+
+	for c := 0; c < cols; c++ {
+		for l := 0; l < rows; l++ {
+			cx := x0 + w/2 + float64(c)*w
+			cy := y0 + s + float64(l)*1.5*s
+
+			if l%2 != 0 {
+				cx += w / 2
+			}
+
+			p := make(Polygon, 6)
+
+			for i := range p {
+				a := math.Pi / 180 * float64(30+60*i)
+				p[i] = f64.Vec2{cx + s*math.Cos(a), cy + s*math.Sin(a)}
+			}
+
+			shapes = append(shapes, p)
+		}
+	}
+
+	return shapeTiles(shapes), shapes
+}
+
+// TriangleGrid returns at least t equilateral triangular tiles that fit in
+// r, along with their shapes. Triangles point up and down alternately and
+// are ordered like Grid.
+func TriangleGrid(r image.Rectangle, t int64) ([]image.Rectangle, []Polygon) {
+	if t < 1 {
+		return nil, nil
+	}
+
+	W, H := float64(r.Dx()), float64(r.Dy())
+	var cols, rows int
+	a := 0.0
+
+	for c := 1; c <= int(t); c++ {
+		l := (int(t) + c - 1) / c
+
+		if side := math.Min(2*W/float64(c+1), H/(float64(l)*math.Sqrt(3)/2)); side > a {
+			a, cols, rows = side, c, l
+		}
+	}
+
+	h := a * math.Sqrt(3) / 2
+	x0 := float64(r.Min.X) + (W-float64(cols+1)*a/2)/2
+	y0 := float64(r.Min.Y) + (H-float64(rows)*h)/2
+	var shapes []Polygon
+
+	for c := 0; c < cols; c++ {
+		for l := 0; l < rows; l++ {
+			x := x0 + float64(c)*a/2
+			y := y0 + float64(l)*h
+
+			if (c+l)%2 == 0 {
+				shapes = append(shapes, Polygon{{x + a/2, y}, {x + a, y + h}, {x, y + h}})
+			} else {
+				shapes = append(shapes, Polygon{{x, y}, {x + a, y}, {x + a/2, y + h}})
+			}
+		}
+	}
+
+	return shapeTiles(shapes), shapes
+}
+
+// shapeTiles returns the bounds of every shape.
+func shapeTiles(shapes []Polygon) []image.Rectangle {
+	tiles := make([]image.Rectangle, len(shapes))
+
+	for i, p := range shapes {
+		tiles[i] = p.Bounds()
+	}
+
+	return tiles
+}
+
+// segmentDistance returns the distance from (x, y) to the segment from a to
+// b.
+func segmentDistance(x, y float64, a, b f64.Vec2) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((x-a[0])*dx+(y-a[1])*dy)/l))
+	}
+
+	return math.Hypot(x-(a[0]+t*dx), y-(a[1]+t*dy))
+}
+
+// coverage converts v to an alpha value, clamping it to 0-1.
+func coverage(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(1, v))*0xff + 0.5)
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package tile
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultFont is the bundled font, Go Regular.
+var DefaultFont = goregular.TTF
+
+// ParseFont parses the given TrueType or OpenType font data. Fonts are safe
+// for concurrent use.
+func ParseFont(data []byte) (*sfnt.Font, error) {
+	return sfnt.Parse(data)
+}
+
+// NewFace returns a face of f with the given size, in pixels. Faces aren't
+// safe for concurrent use.
+func NewFace(f *sfnt.Font, size float64) font.Face {
+	return &face{f: f, ppem: fixed.Int26_6(size*64 + 0.5)}
+}
+
+// Caption is a line of text drawn along with a tile image, below it or over
+// its bottom. For captions below images, the Format used for drawing them
+// should reserve the caption height (see Format.Caption).
+type Caption struct {
+	Face  font.Face
+	Color color.Color
+
+	// Background fills the caption area, if not nil.
+	Background color.Color
+
+	// Over draws the caption over the bottom of the image instead of below
+	// it.
+	Over bool
+}
+
+// Height returns the height of the caption area, the line height plus some
+// padding.
+func (c *Caption) Height() int {
+	h := c.Face.Metrics().Height.Ceil()
+	return h + h/2
+}
+
+// Area returns the caption area of an image drawn with p placement.
+func (c *Caption) Area(p Placement) image.Rectangle {
+	h := c.Height()
+
+	if c.Over {
+		r := p.Dst
+		r.Min.Y = r.Max.Y - h
+		return r.Intersect(p.Dst)
+	}
+
+	r := p.Tile
+	r.Min.Y, r.Max.Y = r.Max.Y, r.Max.Y+h
+	return r
+}
+
+// Draw draws text as the caption of an image drawn with p placement. The
+// text is centered in the caption area and shortened with an ellipsis if it
+// doesn't fit.
+func (c *Caption) Draw(dst draw.Image, p Placement, text string) {
+	r := c.Area(p)
+
+	if r.Empty() || text == "" {
+		return
+	}
+
+	if c.Background != nil {
+		draw.Draw(dst, r, &image.Uniform{c.Background}, image.ZP, draw.Over)
+	}
+
+	m := c.Face.Metrics()
+	pad := m.Height.Ceil() / 4
+	text = fitText(c.Face, text, fixed.I(r.Dx()-2*pad))
+
+	if text == "" {
+		return
+	}
+
+	w := font.MeasureString(c.Face, text)
+	h := m.Ascent + m.Descent
+
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  &image.Uniform{c.Color},
+		Face: c.Face,
+		Dot: fixed.Point26_6{
+			X: fixed.I(r.Min.X) + (fixed.I(r.Dx())-w)/2,
+			Y: fixed.I(r.Min.Y) + (fixed.I(r.Dy())-h)/2 + m.Ascent,
+		},
+	}
+
+	d.DrawString(text)
+}
+
+// fitText returns text shortened with an ellipsis to fit in w width, or an
+// empty string if not even the ellipsis fits.
+func fitText(face font.Face, text string, w fixed.Int26_6) string {
+	if font.MeasureString(face, text) <= w {
+		return text
+	}
+
+	const ellipsis = "…"
+	runes := []rune(text)
+
+	for n := len(runes) - 1; n >= 0; n-- {
+		s := string(runes[:n]) + ellipsis
+
+		if font.MeasureString(face, s) <= w {
+			return s
+		}
+	}
+
+	return ""
+}
@@ -8,6 +8,8 @@ import (
 	"image"
 	"image/color"
 	"io"
+	"math"
+	"sync"
 
 	"golang.org/x/image/draw"
 )
@@ -35,33 +37,52 @@ type Tiler struct {
 
 	bg color.Color
 
-	grid, off int64
+	tiles []image.Rectangle
+	masks []image.Image
+	off   int64
+
+	// mu serializes the drawing of masked tiles, since their bounds may
+	// overlap.
+	mu sync.Mutex
 }
 
 // New returns a Tiler that produces blocks with bg background, s size and t
 // tiles.
 func New(bg color.Color, s image.Rectangle, t int64) *Tiler {
-	if t%2 != 0 {
-		t++
-	}
-
 	img := image.NewRGBA(s)
 	draw.Draw(img, img.Bounds(), &image.Uniform{bg}, image.ZP, draw.Src)
 
 	return &Tiler{
 		Image: img,
 		bg:    bg,
-		grid:  t,
+		tiles: Grid(s, t),
 	}
 }
 
+// NewWithTiles is like New, but it uses the given tiles instead of a grid.
+func NewWithTiles(bg color.Color, s image.Rectangle, tiles []image.Rectangle) *Tiler {
+	t := New(bg, s, 0)
+	t.tiles = tiles
+	return t
+}
+
+// NewWithMasks is like NewWithTiles, but every tile is drawn through the
+// mask with the same index, so tiles may have any shape (see Polygon). Masks
+// use the Tiler coordinates and nil masks leave tiles rectangular.
+func NewWithMasks(bg color.Color, s image.Rectangle, tiles []image.Rectangle, masks []image.Image) *Tiler {
+	t := NewWithTiles(bg, s, tiles)
+	t.masks = masks
+	return t
+}
+
 // Seek implements io.Seeker.
 func (t *Tiler) Seek(offset int64, whence int) (int64, error) {
 	return 0, nil
 }
 
 // DrawAt draws a tile using the r data in off position with f format, returns
-// the used decoder (see image.Decode) and an error, if any.
+// the used decoder (see image.Decode) and an error, if any. Since tiles don't
+// overlap, DrawAt may be called concurrently for different positions.
 func (t *Tiler) DrawAt(r io.Reader, off int64, f *Format) (string, error) {
 	img, df, err := image.Decode(r)
 
@@ -69,28 +90,69 @@ func (t *Tiler) DrawAt(r io.Reader, off int64, f *Format) (string, error) {
 		return df, err
 	}
 
+	t.DrawImageAt(img, off, f)
+	return df, nil
+}
+
+// DrawImageAt is like DrawAt, but it draws an already decoded image and
+// returns its placement.
+func (t *Tiler) DrawImageAt(img image.Image, off int64, f *Format) Placement {
 	if f == nil {
 		f = DefaultFormat
 	}
 
-	var tile image.Rectangle
-	tr := t.Bounds()
+	p := f.Place(t.Tile(off), img.Bounds())
+	tile, img := f.Format(t.Tile(off), img)
 
-	switch off {
-	case 0:
-		tile = image.Rect(0, 0, tr.Max.X/2, tr.Max.Y/2)
-	case 1:
-		tile = image.Rect(0, tr.Max.Y/2, tr.Max.X/2, tr.Max.Y)
-	case 2:
-		tile = image.Rect(tr.Max.X/2, 0, tr.Max.X, tr.Max.Y/2)
-	case 3:
-		tile = image.Rect(tr.Max.X/2, tr.Max.Y/2, tr.Max.X, tr.Max.Y)
+	if mask := t.Mask(off); mask != nil {
+		t.mu.Lock()
+		draw.DrawMask(t.Image, tile, img, img.Bounds().Min, mask, tile.Min, draw.Over)
+		t.mu.Unlock()
+		return p
 	}
 
-	tile, img = f.Format(tile, img)
+	draw.Draw(t.Image, tile, img, img.Bounds().Min, draw.Src)
+	return p
+}
 
-	draw.Draw(t, tile, img, image.ZP, draw.Src)
-	return df, nil
+// FillAt fills the tile in off position with c color, f format margins are
+// respected. It may be used for leaving a placeholder where an image couldn't
+// be drawn.
+func (t *Tiler) FillAt(off int64, c color.Color, f *Format) {
+	if f == nil {
+		f = DefaultFormat
+	}
+
+	tile, _ := f.Format(t.Tile(off), nil)
+
+	if mask := t.Mask(off); mask != nil {
+		t.mu.Lock()
+		draw.DrawMask(t.Image, tile, &image.Uniform{c}, image.ZP, mask, tile.Min, draw.Over)
+		t.mu.Unlock()
+		return
+	}
+
+	draw.Draw(t.Image, tile, &image.Uniform{c}, image.ZP, draw.Src)
+}
+
+// Tile returns the area of the tile in off position, or an empty rectangle if
+// there is no such tile.
+func (t *Tiler) Tile(off int64) image.Rectangle {
+	if off < 0 || off >= int64(len(t.tiles)) {
+		return image.Rectangle{}
+	}
+
+	return t.tiles[off]
+}
+
+// Mask returns the mask of the tile in off position, or nil if it is
+// rectangular.
+func (t *Tiler) Mask(off int64) image.Image {
+	if off < 0 || off >= int64(len(t.masks)) {
+		return nil
+	}
+
+	return t.masks[off]
 }
 
 // Draw is like DrawAt, but it draws at the next position from the current
@@ -103,7 +165,7 @@ func (t *Tiler) Draw(r io.Reader, f *Format) (string, error) {
 		return df, err
 	}
 
-	if t.off == t.grid-1 {
+	if t.off == int64(len(t.tiles))-1 {
 		err = io.EOF
 	}
 
@@ -111,15 +173,86 @@ func (t *Tiler) Draw(r io.Reader, f *Format) (string, error) {
 	return df, err
 }
 
+// Grid returns the t tiles of a grid that fills r, ordered by column (top to
+// bottom, then left to right). Grids have at least 4 tiles, the 2x2 layout of
+// the Tiler, bigger grids are shaped to keep tiles as square as possible and
+// odd t values are rounded up to the next even number.
+func Grid(r image.Rectangle, t int64) []image.Rectangle {
+	if t < 1 {
+		return nil
+	}
+
+	if t <= 4 {
+		return Cells(r, 2, 2)
+	}
+
+	if t%2 != 0 {
+		t++
+	}
+
+	cols, rows := gridShape(r, int(t))
+	return Cells(r, cols, rows)
+}
+
+// Cells returns the tiles of a grid with the given number of columns and rows
+// that fills r, ordered like Grid.
+func Cells(r image.Rectangle, cols, rows int) []image.Rectangle {
+	if cols < 1 || rows < 1 {
+		return nil
+	}
+
+	dx, dy := r.Dx(), r.Dy()
+	tiles := make([]image.Rectangle, 0, cols*rows)
+
+	for c := 0; c < cols; c++ {
+		for l := 0; l < rows; l++ {
+			tiles = append(tiles, image.Rect(
+				r.Min.X+c*dx/cols,
+				r.Min.Y+l*dy/rows,
+				r.Min.X+(c+1)*dx/cols,
+				r.Min.Y+(l+1)*dy/rows,
+			))
+		}
+	}
+
+	return tiles
+}
+
+// gridShape returns the number of columns and rows of a grid with t tiles
+// that fills r with the most square tiles.
+func gridShape(r image.Rectangle, t int) (cols, rows int) {
+	best := math.Inf(1)
+
+	for c := 1; c <= t; c++ {
+		if t%c != 0 {
+			continue
+		}
+
+		l := t / c
+		ratio := (float64(r.Dx()) / float64(c)) / (float64(r.Dy()) / float64(l))
+
+		if d := math.Abs(math.Log(ratio)); d < best {
+			best, cols, rows = d, c, l
+		}
+	}
+
+	return cols, rows
+}
+
 // Format is a set of format options used by Tiler for drawing a tile.
 type Format struct {
 	Margin int64
 	Align  string
 	VAlign string
 	Resize string
+
+	// Caption is the height reserved at the bottom of the tile, after
+	// margins, for a caption (see Caption).
+	Caption int64
 }
 
-// Format returns a tile and an image formatted with f format options.
+// Format returns a tile and an image formatted with f format options. If img
+// is nil, only the tile is formatted.
 func (f *Format) Format(tile image.Rectangle, img image.Image) (image.Rectangle, image.Image) {
 	if f.Margin > 0 {
 		tile.Min.X += int(f.Margin)
@@ -128,7 +261,11 @@ func (f *Format) Format(tile image.Rectangle, img image.Image) (image.Rectangle,
 		tile.Max.Y -= int(f.Margin)
 	}
 
-	if f.Resize != "none" {
+	if f.Caption > 0 {
+		tile.Max.Y -= int(f.Caption)
+	}
+
+	if img != nil && f.Resize != "none" {
 		img = scaleImage(img, tile, f.Resize)
 	}
 
@@ -140,8 +277,66 @@ func (f *Format) Format(tile image.Rectangle, img image.Image) (image.Rectangle,
 	return tile, img
 }
 
-// scaleImage returns a scaled copy of a to b according to mode. There are
-// three modes:
+// Placement describes where an image is drawn inside a tile.
+type Placement struct {
+	// Tile is the tile area after applying margins.
+	Tile image.Rectangle
+
+	// Dst is the tile area covered by the image.
+	Dst image.Rectangle
+
+	// Src is the visible area of the image, in image coordinates.
+	Src image.Rectangle
+
+	// Scale is the factor the image is scaled by.
+	Scale float64
+}
+
+// Place returns the placement of an image with src bounds in tile, as done by
+// Tiler.DrawImageAt, without decoding the image.
+func (f *Format) Place(tile, src image.Rectangle) Placement {
+	tile, _ = f.Format(tile, nil)
+	p := Placement{Tile: tile, Scale: 1}
+	size := src.Size()
+
+	if f.Resize != "none" {
+		size = ScaledSize(src, tile, f.Resize)
+		p.Scale = ScaleFactor(src, tile, f.Resize)
+	}
+
+	p.Dst = image.Rectangle{tile.Min, tile.Min.Add(size)}.Intersect(tile)
+
+	if p.Scale <= 0 {
+		return p
+	}
+
+	p.Src = image.Rect(
+		0,
+		0,
+		int(float64(p.Dst.Dx())/p.Scale+0.5),
+		int(float64(p.Dst.Dy())/p.Scale+0.5),
+	).Add(src.Min).Intersect(src)
+
+	return p
+}
+
+// scaleImage returns a scaled copy of a to b according to mode (see
+// ScaledSize). If a doesn't need to be scaled, it will be returned as is.
+func scaleImage(a, b image.Image, mode string) image.Image {
+	ar := a.Bounds()
+	sz := ScaledSize(ar, b.Bounds(), mode)
+
+	if sz == ar.Size() {
+		return a
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, sz.X, sz.Y))
+	scaler.Scale(dst, dst.Bounds(), a, ar, draw.Over, nil)
+	return dst
+}
+
+// ScaledSize returns the size of a after being scaled to b according to mode.
+// There are three modes:
 //
 // * "auto": scales a if it is bigger.
 //
@@ -149,48 +344,51 @@ func (f *Format) Format(tile image.Rectangle, img image.Image) (image.Rectangle,
 //
 // * "cover": scales a to full fill b.
 //
-// If an invalid mode is given, a will be returned as is.
-func scaleImage(a, b image.Image, mode string) image.Image {
-	var x, y int
-	ar := a.Bounds()
-	br := b.Bounds()
-	ax := ar.Dx()
-	ay := ar.Dy()
-	bx := br.Dx()
-	by := br.Dy()
+// If an invalid mode is given, the size of a will be returned as is.
+func ScaledSize(a, b image.Rectangle, mode string) image.Point {
+	ax := a.Dx()
+	ay := a.Dy()
+	bx := b.Dx()
+	by := b.Dy()
 	xC := getScaleFactor(ax, bx)
 	yC := getScaleFactor(ay, by)
 
 	switch mode {
 	case "auto":
 		if xC >= 1 && yC >= 1 {
-			return a
+			return a.Size()
 		}
 
 		fallthrough
 	case "contain":
 		if xC == 1 || yC == 1 {
-			return a
+			return a.Size()
 		} else if xC < yC {
-			x, y = bx, int(float64(ay)*xC)
-		} else {
-			x, y = int(float64(ax)*yC), by
+			return image.Pt(bx, int(float64(ay)*xC))
 		}
+
+		return image.Pt(int(float64(ax)*yC), by)
 	case "cover":
 		if xC == 1 || yC == 1 {
-			return a
+			return a.Size()
 		} else if xC < yC {
-			x, y = int(float64(ax)*yC), by
-		} else {
-			x, y = bx, int(float64(ay)*xC)
+			return image.Pt(int(float64(ax)*yC), by)
 		}
-	default:
-		return a
+
+		return image.Pt(bx, int(float64(ay)*xC))
 	}
 
-	dst := image.NewRGBA(image.Rect(0, 0, x, y))
-	scaler.Scale(dst, dst.Bounds(), a, a.Bounds(), draw.Over, nil)
-	return dst
+	return a.Size()
+}
+
+// ScaleFactor returns the factor a is scaled by when drawn in b according to
+// mode (see ScaledSize).
+func ScaleFactor(a, b image.Rectangle, mode string) float64 {
+	if a.Dx() == 0 {
+		return 1
+	}
+
+	return float64(ScaledSize(a, b, mode).X) / float64(a.Dx())
 }
 
 // getScaleFactor returns the scale factor for a to be similar to b.
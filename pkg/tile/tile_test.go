@@ -0,0 +1,115 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package tile
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	portrait := image.Rect(0, 0, 100, 200)
+	landscape := image.Rect(0, 0, 300, 200)
+
+	quadrants := []image.Rectangle{
+		image.Rect(0, 0, 50, 100),
+		image.Rect(0, 100, 50, 200),
+		image.Rect(50, 0, 100, 100),
+		image.Rect(50, 100, 100, 200),
+	}
+
+	// 3 columns and 2 rows of 100x100 tiles.
+	six := []image.Rectangle{
+		image.Rect(0, 0, 100, 100),
+		image.Rect(0, 100, 100, 200),
+		image.Rect(100, 0, 200, 100),
+		image.Rect(100, 100, 200, 200),
+		image.Rect(200, 0, 300, 100),
+		image.Rect(200, 100, 300, 200),
+	}
+
+	// 2 columns and 4 rows of 100x100 tiles.
+	tall := []image.Rectangle{
+		image.Rect(0, 0, 100, 100),
+		image.Rect(0, 100, 100, 200),
+		image.Rect(0, 200, 100, 300),
+		image.Rect(0, 300, 100, 400),
+		image.Rect(100, 0, 200, 100),
+		image.Rect(100, 100, 200, 200),
+		image.Rect(100, 200, 200, 300),
+		image.Rect(100, 300, 200, 400),
+	}
+
+	// 4 columns and 2 rows of 100x50 tiles.
+	wide := []image.Rectangle{
+		image.Rect(0, 0, 100, 50),
+		image.Rect(0, 50, 100, 100),
+		image.Rect(100, 0, 200, 50),
+		image.Rect(100, 50, 200, 100),
+		image.Rect(200, 0, 300, 50),
+		image.Rect(200, 50, 300, 100),
+		image.Rect(300, 0, 400, 50),
+		image.Rect(300, 50, 400, 100),
+	}
+
+	cases := []struct {
+		r    image.Rectangle
+		t    int64
+		want []image.Rectangle
+	}{
+		{portrait, 0, nil},
+		{portrait, -1, nil},
+		{portrait, 1, quadrants},
+		{portrait, 2, quadrants},
+		{portrait, 3, quadrants},
+		{portrait, 4, quadrants},
+		{landscape, 5, six},
+		{landscape, 6, six},
+		{image.Rect(0, 0, 200, 400), 8, tall},
+		{image.Rect(0, 0, 400, 100), 8, wide},
+	}
+
+	for _, c := range cases {
+		if got := Grid(c.r, c.t); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Grid(%v, %d) = %v, want %v", c.r, c.t, got, c.want)
+		}
+	}
+}
+
+func TestCells(t *testing.T) {
+	cases := []struct {
+		r          image.Rectangle
+		cols, rows int
+		want       []image.Rectangle
+	}{
+		{image.Rect(0, 0, 10, 10), 0, 1, nil},
+		{image.Rect(0, 0, 10, 10), 1, 0, nil},
+		{image.Rect(0, 0, 10, 10), 1, 1, []image.Rectangle{image.Rect(0, 0, 10, 10)}},
+		{
+			image.Rect(10, 10, 110, 60), 2, 1,
+			[]image.Rectangle{image.Rect(10, 10, 60, 60), image.Rect(60, 10, 110, 60)},
+		},
+		{
+			image.Rect(0, 0, 10, 10), 3, 1,
+			[]image.Rectangle{
+				image.Rect(0, 0, 3, 10),
+				image.Rect(3, 0, 6, 10),
+				image.Rect(6, 0, 10, 10),
+			},
+		},
+		{
+			image.Rect(0, 0, 20, 20), 1, 2,
+			[]image.Rectangle{image.Rect(0, 0, 20, 10), image.Rect(0, 10, 20, 20)},
+		},
+	}
+
+	for _, c := range cases {
+		got := Cells(c.r, c.cols, c.rows)
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Cells(%v, %d, %d) = %v, want %v", c.r, c.cols, c.rows, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// runPoster enlarges one image across a grid of pages, so it can be printed
+// and assembled as a poster.
+func runPoster(args []string) {
+	var (
+		verbose bool
+		size    string
+		bg      string
+		pages   string
+		target  string
+		overlap string
+		margin  string
+		marks   bool
+		output  string
+	)
+
+	fs := flag.NewFlagSet("poster", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	fs.StringVar(&size, "size", "letter300", "Page size")
+	fs.StringVar(&bg, "bg", "white", "Page background color")
+
+	fs.StringVar(
+		&pages,
+		"pages",
+		"2x2",
+		"Number of pages, as COLUMNSxROWS",
+	)
+
+	fs.StringVar(
+		&target,
+		"target",
+		"",
+		"Physical poster size (e.g. 90x120cm, 36x48in), overrides -pages",
+	)
+
+	fs.StringVar(
+		&overlap,
+		"overlap",
+		"0.5in",
+		"Overlap between neighbor pages (px, in, cm, mm or pt)",
+	)
+
+	fs.StringVar(
+		&margin,
+		"margin",
+		"0",
+		"Unprintable page margin (px, in, cm, mm or pt)",
+	)
+
+	fs.BoolVar(
+		&marks,
+		"marks",
+		false,
+		"Draw alignment marks at the overlap boundaries",
+	)
+
+	fs.StringVar(
+		&output,
+		"o",
+		"poster%d.jpg",
+		"Output file, %d in file name is replaced by page number",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() != 1 {
+		log.Fatalln("Exactly 1 image should be given")
+	}
+
+	page, ok := OutputSizes[size]
+
+	if !ok {
+		log.Fatalf("Unknown page size '%s'\n", size)
+	}
+
+	dpi := OutputDPI[size]
+	ov, err := parseLength(overlap, dpi)
+
+	if err != nil {
+		log.Fatalf("Invalid overlap -> %v\n", err)
+	}
+
+	m, err := parseLength(margin, dpi)
+
+	if err != nil {
+		log.Fatalf("Invalid margin -> %v\n", err)
+	}
+
+	content := page.Inset(m)
+	cw, ch := content.Dx(), content.Dy()
+
+	if ov < 0 || ov >= cw || ov >= ch {
+		log.Fatalln("The overlap should be smaller than the printable page area")
+	}
+
+	src, err := fileSource(fs.Arg(0)).decode()
+
+	if err != nil {
+		log.Fatalf("Can't decode the image '%s' -> %v\n", fs.Arg(0), err)
+	}
+
+	sb := src.Bounds()
+	var cols, rows int
+	var poster image.Point
+
+	if target != "" {
+		t, err := parseSize(target, dpi)
+
+		if err != nil {
+			log.Fatalf("Invalid poster size -> %v\n", err)
+		}
+
+		poster = tile.ScaledSize(sb, image.Rectangle{Max: t}, "contain")
+		cols = pagesFor(poster.X, cw, ov)
+		rows = pagesFor(poster.Y, ch, ov)
+	} else {
+		if _, err := fmt.Sscanf(pages, "%dx%d", &cols, &rows); err != nil || cols < 1 || rows < 1 {
+			log.Fatalf("Invalid number of pages '%s'\n", pages)
+		}
+
+		area := image.Rect(0, 0, cols*(cw-ov)+ov, rows*(ch-ov)+ov)
+		poster = tile.ScaledSize(sb, area, "contain")
+	}
+
+	scale := float64(poster.X) / float64(sb.Dx())
+
+	// The poster is centered in the area covered by the pages.
+	off := image.Pt(
+		(cols*(cw-ov)+ov-poster.X)/2,
+		(rows*(ch-ov)+ov-poster.Y)/2,
+	)
+
+	if verbose {
+		fmt.Printf("Poster of %dx%d px in %dx%d pages", poster.X, poster.Y, cols, rows)
+
+		if dpi > 0 {
+			fmt.Printf(
+				" (%.1fx%.1f cm)",
+				float64(poster.X)/float64(dpi)*2.54,
+				float64(poster.Y)/float64(dpi)*2.54,
+			)
+		}
+
+		fmt.Println()
+	}
+
+	var fails failures
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			n := r*cols + c
+			dst := image.NewRGBA(page)
+			draw.Draw(dst, page, &image.Uniform{colornames.Map[bg]}, image.ZP, draw.Src)
+
+			// Page origin in poster coordinates.
+			o := image.Pt(c*(cw-ov), r*(ch-ov)).Sub(off)
+
+			s2d := f64.Aff3{
+				scale, 0, float64(content.Min.X-o.X) - float64(sb.Min.X)*scale,
+				0, scale, float64(content.Min.Y-o.Y) - float64(sb.Min.Y)*scale,
+			}
+
+			area := dst.SubImage(content).(*image.RGBA)
+			draw.ApproxBiLinear.Transform(area, s2d, src, sb, draw.Over, nil)
+
+			if marks {
+				drawPosterMarks(dst, content, ov, dpi, c > 0, c < cols-1, r > 0, r < rows-1)
+			}
+
+			name := expandName(output, nil, int64(n))
+
+			if verbose {
+				fmt.Printf("Page #%d (column %d, row %d) -> '%s'\n", n, c, r, name)
+			}
+
+			if err := writeImage(dirSink{}, name, dst); err != nil {
+				fails.add(name, "", err)
+			}
+		}
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// pagesFor returns the number of pages with l printable length and ov overlap
+// needed to cover n pixels.
+func pagesFor(n, l, ov int) int {
+	p := (n - ov + (l - ov) - 1) / (l - ov)
+
+	if p < 1 {
+		return 1
+	}
+
+	return p
+}
+
+// drawPosterMarks draws dashed lines at the overlap boundaries of the content
+// area of a page, at the sides that have a neighbor page.
+func drawPosterMarks(dst *image.RGBA, content image.Rectangle, ov, dpi int, left, right, top, bottom bool) {
+	w := dpi / 150
+	dash := dpi / 10
+
+	if dash < 4 {
+		dash = 4
+	}
+
+	c := colornames.Gray
+	var xs, ys []int
+
+	if left {
+		xs = append(xs, content.Min.X+ov)
+	}
+
+	if right {
+		xs = append(xs, content.Max.X-ov)
+	}
+
+	if top {
+		ys = append(ys, content.Min.Y+ov)
+	}
+
+	if bottom {
+		ys = append(ys, content.Max.Y-ov)
+	}
+
+	for _, x := range xs {
+		tile.DrawLine(dst, image.Pt(x, content.Min.Y), image.Pt(x, content.Max.Y), w, c, dash)
+	}
+
+	for _, y := range ys {
+		tile.DrawLine(dst, image.Pt(content.Min.X, y), image.Pt(content.Max.X, y), w, c, dash)
+	}
+
+	for _, x := range xs {
+		for _, y := range ys {
+			tile.DrawCross(dst, image.Pt(x, y), 2*dash, 2*w+1, colornames.Black)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"path/filepath"
+
+	"golang.org/x/image/colornames"
+
+	"github.com/ntrrg/tiler/pkg/pyramid"
+)
+
+// runPyramid cuts an image into a zoomable tile pyramid.
+func runPyramid(args []string) {
+	var (
+		verbose bool
+		format  string
+		bg      string
+		output  string
+
+		opts = *pyramid.DefaultOptions
+	)
+
+	fs := flag.NewFlagSet("pyramid", flag.ExitOnError)
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	fs.StringVar(&opts.Layout, "layout", opts.Layout, "Pyramid layout, 'dzi' or 'xyz'")
+	fs.IntVar(&opts.TileSize, "tile-size", opts.TileSize, "Tiles width and height")
+	fs.IntVar(&opts.Overlap, "overlap", opts.Overlap, "Tiles overlap (DZI only)")
+	fs.StringVar(&format, "format", "png", "Tiles format, 'png' or 'jpg'")
+
+	fs.StringVar(
+		&bg,
+		"bg",
+		"",
+		"Background color of the padding of XYZ edge tiles, transparent if empty",
+	)
+
+	fs.StringVar(
+		&output,
+		"o",
+		"pyramid",
+		"Output name, DZI pyramids are written as NAME.dzi and NAME_files/, "+
+			"XYZ pyramids as NAME/",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() != 1 {
+		log.Fatalln("Exactly 1 image should be given")
+	}
+
+	if format != "png" && format != "jpg" {
+		log.Fatalf("Unsupported tiles format '%s'\n", format)
+	}
+
+	if bg != "" {
+		opts.Background = colornames.Map[bg]
+	}
+
+	img, err := fileSource(fs.Arg(0)).decode()
+
+	if err != nil {
+		log.Fatalf("Can't decode the image '%s' -> %v\n", fs.Arg(0), err)
+	}
+
+	output = filepath.Clean(output)
+	dir := output
+
+	if opts.Layout == pyramid.DZI {
+		dir += "_files"
+	}
+
+	n := 0
+
+	info, err := pyramid.Generate(img, &opts, func(l, c, r int, t image.Image) error {
+		name := fmt.Sprintf("%d/%d_%d.%s", l, c, r, format)
+
+		if opts.Layout == pyramid.XYZ {
+			name = fmt.Sprintf("%d/%d/%d.%s", l, c, r, format)
+		}
+
+		n++
+		return writeImage(dirSink{}, filepath.Join(dir, filepath.FromSlash(name)), t)
+	})
+
+	if err != nil {
+		log.Fatalf("Can't generate the pyramid -> %v\n", err)
+	}
+
+	var desc string
+
+	if opts.Layout == pyramid.DZI {
+		desc = output + ".dzi"
+		err = writeDescriptor(desc, func(w io.Writer) error {
+			return pyramid.WriteDZI(w, info, format, &opts)
+		})
+	} else {
+		desc = filepath.Join(output, "tiles.json")
+		err = writeDescriptor(desc, func(w io.Writer) error {
+			return pyramid.WriteXYZ(w, info, "{z}/{x}/{y}."+format, &opts)
+		})
+	}
+
+	if err != nil {
+		log.Fatalf("Can't write the pyramid descriptor -> %v\n", err)
+	}
+
+	if verbose {
+		fmt.Printf(
+			"%d tiles in %d levels written, descriptor at '%s'\n",
+			n,
+			info.MaxLevel+1,
+			desc,
+		)
+	}
+}
+
+// writeDescriptor creates the file at p and writes its content using fn.
+func writeDescriptor(p string, fn func(io.Writer) error) error {
+	w, err := dirSink{}.Create(p)
+
+	if err != nil {
+		return err
+	}
+
+	err = fn(w)
+
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ntrrg/tiler/pkg/tile"
+)
+
+// runSplit extracts the tiles of the given sheets into individual files,
+// using a layout manifest (see -manifest) or a grid spec.
+func runSplit(args []string) {
+	var (
+		manifest string
+		tiles    int64
+		trim     bool
+		output   string
+
+		format = *tile.DefaultFormat
+	)
+
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+
+	fs.StringVar(
+		&manifest,
+		"manifest",
+		"",
+		"Layout manifest of the sheets, if not given, the grid spec is used",
+	)
+
+	fs.Int64Var(&tiles, "tiles", 4, "Number of tiles of the grid spec")
+	fs.Int64Var(&format.Margin, "margin", 0, "Margin of the grid spec")
+
+	fs.BoolVar(
+		&trim,
+		"trim",
+		false,
+		"Trim margins, with a manifest only the image area is extracted",
+	)
+
+	fs.StringVar(
+		&output,
+		"o",
+		"{sheet}-%d.png",
+		"Output file, %d in file name is replaced by tile number, {sheet} by "+
+			"the sheet name and {image} by the source image name (requires a "+
+			"manifest)",
+	)
+
+	fs.Parse(args)
+	log.SetFlags(0)
+
+	if fs.NArg() < 1 {
+		log.Fatalln("At least 1 sheet should be given")
+	}
+
+	var layouts []sheetLayout
+
+	if manifest != "" {
+		var err error
+		layouts, err = readManifest(manifest)
+
+		if err != nil {
+			log.Fatalf("Can't read the manifest -> %v\n", err)
+		}
+	}
+
+	var fails failures
+
+	for _, p := range fs.Args() {
+		if err := splitSheet(p, layouts, tiles, &format, trim, output); err != nil {
+			fails.add("", p, err)
+		}
+	}
+
+	if fails.len() > 0 {
+		fails.report(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// splitSheet extracts the tiles of the sheet at p. If layouts is empty, tiles
+// are computed from the grid spec.
+func splitSheet(p string, layouts []sheetLayout, tiles int64, f *tile.Format, trim bool, output string) error {
+	src, err := fileSource(p).decode()
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		slots  []slotLayout
+		sx, sy = 1.0, 1.0
+	)
+
+	if len(layouts) > 0 {
+		l, ok := findLayout(layouts, p)
+
+		if !ok {
+			return fmt.Errorf("not found in the manifest")
+		}
+
+		// The sheet may have been scanned back at a different resolution.
+		if l.Width > 0 && l.Height > 0 {
+			sx = float64(src.Bounds().Dx()) / float64(l.Width)
+			sy = float64(src.Bounds().Dy()) / float64(l.Height)
+		}
+
+		slots = l.Slots
+	} else {
+		for i, cell := range tile.Grid(src.Bounds(), tiles) {
+			r, _ := f.Format(cell, nil)
+
+			slots = append(slots, slotLayout{
+				Slot: int64(i),
+				Cell: newRect(cell),
+				Dest: newRect(r),
+			})
+		}
+	}
+
+	sheet := strings.TrimSuffix(p, filepath.Ext(p))
+
+	for _, s := range slots {
+		r := s.Cell.Rectangle()
+
+		if trim {
+			r = s.Dest.Rectangle()
+		}
+
+		if s.Error != "" || r.Empty() {
+			continue
+		}
+
+		r = image.Rect(
+			int(float64(r.Min.X)*sx),
+			int(float64(r.Min.Y)*sy),
+			int(float64(r.Max.X)*sx),
+			int(float64(r.Max.Y)*sy),
+		).Add(src.Bounds().Min)
+
+		vars := map[string]string{"sheet": sheet}
+
+		if s.Image != "" {
+			img := filepath.Base(s.Image)
+			vars["image"] = strings.TrimSuffix(img, filepath.Ext(img))
+		}
+
+		name := expandName(output, vars, s.Slot)
+
+		if err := writeImage(dirSink{}, name, crop(src, r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readManifest reads the layouts from the manifest at p, it may be a sheet or
+// a run manifest.
+func readManifest(p string) ([]sheetLayout, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(p))
+
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("[")) {
+		var layouts []sheetLayout
+		err = json.Unmarshal(data, &layouts)
+		return layouts, err
+	}
+
+	var l sheetLayout
+	err = json.Unmarshal(data, &l)
+	return []sheetLayout{l}, err
+}
+
+// findLayout returns the layout of the sheet at p. If there is only one
+// layout, it is used for any sheet.
+func findLayout(layouts []sheetLayout, p string) (sheetLayout, bool) {
+	if len(layouts) == 1 {
+		return layouts[0], true
+	}
+
+	for _, l := range layouts {
+		if filepath.Clean(l.Output) == filepath.Clean(p) {
+			return l, true
+		}
+	}
+
+	for _, l := range layouts {
+		if filepath.Base(l.Output) == filepath.Base(p) {
+			return l, true
+		}
+	}
+
+	return sheetLayout{}, false
+}
+
+// crop returns the r area of img.
+func crop(img image.Image, r image.Rectangle) image.Image {
+	r = r.Intersect(img.Bounds())
+
+	if si, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return si.SubImage(r)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Units is the length of the supported units, in inches.
+var Units = map[string]float64{
+	"in": 1,
+	"cm": 1 / 2.54,
+	"mm": 1 / 25.4,
+	"pt": 1.0 / 72,
+}
+
+// parseLength returns the length s in pixels at dpi. s is a number followed
+// by an optional unit (see Units), pixels are used if no unit is given.
+func parseLength(s string, dpi int) (int, error) {
+	v, unit := splitUnit(s)
+	n, err := strconv.ParseFloat(v, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid length '%s'", s)
+	}
+
+	return toPixels(n, unit, dpi)
+}
+
+// parseSize returns the size s ("WxH", with an optional unit shared by both
+// dimensions, like "90x120cm") in pixels at dpi.
+func parseSize(s string, dpi int) (image.Point, error) {
+	w, h, err := parseExactSize(s, dpi)
+
+	if err != nil {
+		return image.Point{}, err
+	}
+
+	return image.Pt(int(math.Round(w)), int(math.Round(h))), nil
+}
+
+// parseExactSize is like parseSize, but it doesn't round the result.
+func parseExactSize(s string, dpi int) (float64, float64, error) {
+	v, unit := splitUnit(s)
+	parts := strings.Split(strings.ToLower(v), "x")
+
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	var p [2]float64
+
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid size '%s'", s)
+		}
+
+		if p[i], err = toExactPixels(n, unit, dpi); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return p[0], p[1], nil
+}
+
+// splitUnit splits s into its numeric value and its unit.
+func splitUnit(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := len(s)
+
+	for i > 0 && (s[i-1] >= 'a' && s[i-1] <= 'z' || s[i-1] >= 'A' && s[i-1] <= 'Z') {
+		i--
+	}
+
+	return s[:i], strings.ToLower(s[i:])
+}
+
+// toPixels returns n unit in pixels at dpi.
+func toPixels(n float64, unit string, dpi int) (int, error) {
+	px, err := toExactPixels(n, unit, dpi)
+	return int(math.Round(px)), err
+}
+
+// toExactPixels is like toPixels, but it doesn't round the result.
+func toExactPixels(n float64, unit string, dpi int) (float64, error) {
+	if unit == "" || unit == "px" {
+		return n, nil
+	}
+
+	in, ok := Units[unit]
+
+	if !ok {
+		return 0, fmt.Errorf("unknown unit '%s'", unit)
+	}
+
+	if dpi <= 0 {
+		return 0, fmt.Errorf("physical units require a known resolution")
+	}
+
+	return n * in * float64(dpi), nil
+}
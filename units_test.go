@@ -0,0 +1,46 @@
+// Copyright 2018 Miguel Angel Rivera Notararigo. All rights reserved.
+// This source code was released under the MIT license.
+
+package main
+
+import "testing"
+
+func TestParseLength(t *testing.T) {
+	cases := []struct {
+		s    string
+		dpi  int
+		want int
+		err  bool
+	}{
+		{"10", 300, 10, false},
+		{"10px", 300, 10, false},
+		{"10PX", 0, 10, false},
+		{"1.6", 300, 2, false},
+		{"-3px", 300, -3, false},
+		{"1in", 300, 300, false},
+		{"2.54cm", 300, 300, false},
+		{"25.4mm", 200, 200, false},
+		{" 3MM ", 300, 35, false},
+		{"72pt", 72, 72, false},
+		{"10pt", 300, 42, false},
+		{"", 300, 0, true},
+		{"mm", 300, 0, true},
+		{"abc", 300, 0, true},
+		{"1.2.3mm", 300, 0, true},
+		{"10furlong", 300, 0, true},
+		{"1in", 0, 0, true},
+	}
+
+	for _, c := range cases {
+		got, err := parseLength(c.s, c.dpi)
+
+		if (err != nil) != c.err {
+			t.Errorf("parseLength(%q, %d): got error %v, want error %v", c.s, c.dpi, err, c.err)
+			continue
+		}
+
+		if err == nil && got != c.want {
+			t.Errorf("parseLength(%q, %d) = %d, want %d", c.s, c.dpi, got, c.want)
+		}
+	}
+}